@@ -1,3 +1,5 @@
 module github.com/yue-qiu/imgProc
 
 go 1.13
+
+require golang.org/x/image v0.0.0-20210628002857-a66eb6448b8d
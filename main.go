@@ -1,14 +1,21 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"image"
+	"image/color"
 	"github.com/yue-qiu/imgProc/tool"
 	"io/ioutil"
 	"os"
 	"os/signal"
 	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 )
 
 type App struct {
@@ -18,6 +25,11 @@ type App struct {
 }
 
 func main() {
+	// 子命令模式：./imgProc dedupe [flags]
+	if len(os.Args) > 1 && os.Args[1] == "dedupe" {
+		os.Exit(dedupeCommand(os.Args[2:]))
+	}
+
 	app, err := NewApp()
 	if err != nil {
 		fmt.Println(err.Error())
@@ -77,6 +89,102 @@ func (app App)Run() {
 			app.dealWithAdjBrit()
 		case strings.ToLower("ToASCII"):
 			app.dealWithToASCII()
+		case strings.ToLower("Threshold"):
+			app.dealWithThreshold()
+		case strings.ToLower("AdaptiveThreshold"):
+			app.dealWithAdaptiveThreshold()
+		case strings.ToLower("Histogram"):
+			app.dealWithHistogram()
+		case strings.ToLower("Equalize"):
+			app.dealWithEqualize()
+		case strings.ToLower("MatchHistogram"):
+			app.dealWithMatchHistogram()
+		case strings.ToLower("CLAHE"):
+			app.dealWithCLAHE()
+		case strings.ToLower("AdjustContrast"):
+			app.dealWithContrast()
+		case strings.ToLower("AdjustGamma"):
+			app.dealWithGamma()
+		case strings.ToLower("AdjustExposure"):
+			app.dealWithExposure()
+		case strings.ToLower("AutoLevels"):
+			app.dealWithAutoLevels()
+		case strings.ToLower("Levels"):
+			app.dealWithLevels()
+		case strings.ToLower("Curves"):
+			app.dealWithCurves()
+		case strings.ToLower("ApplyLUT"):
+			app.dealWithApplyLUT()
+		case strings.ToLower("ExportLUT"):
+			app.dealWithExportLUT()
+		case strings.ToLower("HueRotate"):
+			app.dealWithHueRotate()
+		case strings.ToLower("Saturation"):
+			app.dealWithSaturation()
+		case strings.ToLower("Vibrance"):
+			app.dealWithVibrance()
+		case strings.ToLower("SelectiveColor"):
+			app.dealWithSelectiveColor()
+		case strings.ToLower("WhiteBalance"):
+			app.dealWithWhiteBalance()
+		case strings.ToLower("Sepia"):
+			app.dealWithSepia()
+		case strings.ToLower("Vintage"):
+			app.dealWithVintage()
+		case strings.ToLower("CrossProcess"):
+			app.dealWithCrossProcess()
+		case strings.ToLower("Duotone"):
+			app.dealWithDuotone()
+		case strings.ToLower("Solarize"):
+			app.dealWithSolarize()
+		case strings.ToLower("Posterize"):
+			app.dealWithPosterize()
+		case strings.ToLower("Emboss"):
+			app.dealWithEmboss()
+		case strings.ToLower("Vignette"):
+			app.dealWithVignette()
+		case strings.ToLower("ContactSheet"):
+			app.dealWithContactSheet()
+		case strings.ToLower("Orientation"):
+			app.dealWithOrientation()
+		case strings.ToLower("RotateAngle"):
+			app.dealWithRotateAngle()
+		case strings.ToLower("Thumbnail"):
+			app.dealWithThumbnail()
+		case strings.ToLower("Crop"):
+			app.dealWithCrop()
+		case strings.ToLower("Pad"):
+			app.dealWithPad()
+		case strings.ToLower("Canvas"):
+			app.dealWithCanvas()
+		case strings.ToLower("ROI"):
+			app.dealWithROI()
+		case strings.ToLower("Affine"):
+			app.dealWithAffine()
+		case strings.ToLower("Perspective"):
+			app.dealWithPerspective()
+		case strings.ToLower("SeamCarve"):
+			app.dealWithSeamCarve()
+		case strings.ToLower("RemoveObject"):
+			app.dealWithRemoveObject()
+		case strings.ToLower("Watermark"):
+			app.dealWithWatermark()
+		case strings.ToLower("Text"):
+			app.dealWithText()
+		case strings.ToLower("Draw"):
+			app.dealWithDraw()
+		case strings.ToLower("Alpha"):
+			app.dealWithAlpha()
+		case strings.ToLower("ChromaKey"):
+			app.dealWithChromaKey()
+		case strings.ToLower("Hash"):
+			app.dealWithHash()
+		case strings.ToLower("Compare"):
+			app.dealWithCompare()
+		case strings.ToLower("Dedupe"):
+			app.dealWithDedupe()
+		case strings.ToLower("Quality"):
+			app.dealWithQuality()
 		}
 	}
 }
@@ -129,6 +237,28 @@ func (app App)getChoice() string {
 	return choice
 }
 
+// 读取一整行（可以包含空格），跳过上一次输入残留的换行
+func (app App)readLine() string {
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n == 0 || err != nil {
+			break
+		}
+		if buf[0] == '\n' {
+			if len(strings.TrimSpace(string(line))) == 0 {
+				line = line[:0]
+				continue
+			}
+			break
+		}
+		line = append(line, buf[0])
+	}
+
+	return strings.TrimRight(string(line), "\r")
+}
+
 func (app App)checkRawChoice(choice string) bool {
 	var isValid bool
 
@@ -297,6 +427,76 @@ func (app App)dealWithFingerPrint() {
 	fmt.Println()
 }
 
+func (app App)dealWithHash() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Printf("input algorithm(%s) & hash size, separated by space(e.g. phash 8): ", strings.Join(tool.HasherNames(), ", "))
+	var name string
+	var size int
+	_, err := fmt.Scan(&name, &size)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	hasher, err := tool.NewHasher(name, size)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	hash := hasher.Hash(&il)
+	fmt.Printf("%s(%d bits): %s\n", hasher.Name(), hash.Len(), hash.Hex())
+	fmt.Println()
+}
+
+func (app App)dealWithCompare() {
+	app.listRaw()
+	fmt.Printf("choose two images(seperated by space): ")
+	var filename1, filename2 string
+	_, _ = fmt.Scan(&filename1, &filename2)
+	if isValid1, isValid2 := app.checkRawChoice(filename1), app.checkRawChoice(filename2); !isValid1 || !isValid2 {
+		fmt.Println("inValid input!")
+		return
+	}
+
+	fmt.Print("input the max hamming distance to be considered similar(0 - 64, e.g. 10): ")
+	var threshold int
+	_, err := fmt.Scan(&threshold)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	il1, err := tool.NewImgLoader(path.Join(tool.RAW, filename1))
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	il2, err := tool.NewImgLoader(path.Join(tool.RAW, filename2))
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	distance, similarity, err := tool.CompareFingerprints(app.Processor.GetFingerPrint(&il1), app.Processor.GetFingerPrint(&il2))
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Printf("hamming distance: %d, similarity: %.2f%%\n", distance, similarity*100)
+	if distance <= threshold {
+		fmt.Println("match: the two images look similar")
+	} else {
+		fmt.Println("no match: the two images look different")
+	}
+	fmt.Println()
+}
+
 func (app App)dealWithBase64Enc() {
 	app.listRaw()
 	filename := app.getChoice()
@@ -386,8 +586,17 @@ func (app App)dealWithResize() {
 		return
 	}
 
+	fmt.Printf("input resample filter(%s): ", strings.Join(tool.ResampleFilterNames(), ", "))
+	var name string
+	_, _ = fmt.Scan(&name)
+	filter, err := tool.ParseResampleFilter(name)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
 	savePath := path.Join(tool.RESULT, "Resize-"+il.GetFileName()+".png")
-	err = tool.SaveAsPng( savePath, app.Processor.Resize(&il, height, width).GetMatrix())
+	err = tool.SaveAsPng( savePath, app.Processor.ResizeWith(&il, height, width, filter).GetMatrix())
 	if err != nil {
 		fmt.Println(err.Error())
 		return
@@ -418,8 +627,22 @@ func (app App)dealWithFusion() {
 		return
 	}
 
+	fmt.Printf("input blend mode(%s) & opacity [0, 1], separated by space: ", strings.Join(tool.BlendModeNames(), ", "))
+	var name string
+	var opacity float64
+	_, err = fmt.Scan(&name, &opacity)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	mode, err := tool.ParseBlendMode(name)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
 	savePath := path.Join(tool.RESULT, "fusion-"+il1.GetFileName()+il2.GetFileName()+".png")
-	err = tool.SaveAsPng( savePath, app.Processor.ImageFusion(&il1, &il2).GetMatrix())
+	err = tool.SaveAsPng( savePath, app.Processor.Blend(&il1, &il2, mode, opacity).GetMatrix())
 	if err != nil {
 		fmt.Println(err.Error())
 		return
@@ -443,20 +666,119 @@ func (app App)dealWithAdjBrit() {
 		return
 	}
 
-	fmt.Print("input brightness rate(Negative numbers will be treated as 0): ")
-	var light float64
-	_, err = fmt.Scan(&light)
+	fmt.Print("input brightness offset in [-255, 255]: ")
+	var offset float64
+	_, err = fmt.Scan(&offset)
 	if err != nil {
 		fmt.Println(err.Error())
 		return
 	}
 
-	if light < 0 {
-		light = 0
+	savePath := path.Join(tool.RESULT, "AdjBrit-"+il.GetFileName()+".png")
+	err = tool.SaveAsPng( savePath, app.Processor.AdjustBrightness(&il, offset).GetMatrix())
+	if err != nil {
+		fmt.Println(err.Error())
+		return
 	}
 
-	savePath := path.Join(tool.RESULT, "AdjBrit-"+il.GetFileName()+".png")
-	err = tool.SaveAsPng( savePath, app.Processor.AdjustBrightness(&il, light).GetMatrix())
+	fmt.Println("Succeed, enjoy it")
+	fmt.Println()
+}
+
+func (app App)dealWithThreshold() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input method(fixed, otsu, triangle): ")
+	var method string
+	_, _ = fmt.Scan(&method)
+
+	var t uint8
+	var m tool.ThresholdMethod
+	switch strings.ToLower(method) {
+	case "fixed":
+		m = tool.ThresholdFixed
+		fmt.Printf("input threshold in [0, 255](negative numbers for default %d): ", tool.ASCIITHRESTOLD)
+		var value int
+		_, err := fmt.Scan(&value)
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		if value < 0 {
+			value = tool.ASCIITHRESTOLD
+		} else if value > 255 {
+			value = 255
+		}
+		t = uint8(value)
+	case "otsu":
+		m = tool.ThresholdOtsu
+	case "triangle":
+		m = tool.ThresholdTriangle
+	default:
+		fmt.Printf("Error, invalid method: %s\n", method)
+		return
+	}
+
+	mask, t := app.Processor.Binarize(&il, m, t)
+	fmt.Println("threshold: ", t)
+	app.saveBinary("Threshold", mask)
+}
+
+func (app App)dealWithAdaptiveThreshold() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input method(mean, gaussian, sauvola, niblack): ")
+	var method string
+	_, _ = fmt.Scan(&method)
+
+	var m tool.AdaptiveMethod
+	switch strings.ToLower(method) {
+	case "mean":
+		m = tool.AdaptiveMean
+	case "gaussian":
+		m = tool.AdaptiveGaussian
+	case "sauvola":
+		m = tool.AdaptiveSauvola
+	case "niblack":
+		m = tool.AdaptiveNiblack
+	default:
+		fmt.Printf("Error, invalid method: %s\n", method)
+		return
+	}
+
+	fmt.Print("input block size & k, separated by space(e.g. 25 10 for mean, 25 0.3 for sauvola): ")
+	var blockSize int
+	var k float64
+	_, err := fmt.Scan(&blockSize, &k)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveBinary("AdaptiveThreshold", app.Processor.AdaptiveBinarize(&il, m, blockSize, k))
+}
+
+func (app App)dealWithHistogram() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	hist := app.Processor.GetHistogram(&il)
+	var sum int
+	for v, n := range hist.Luma {
+		sum += v * n
+	}
+	fmt.Printf("pixels: %d, mean luma: %.2f\n", hist.Total(), float64(sum)/float64(hist.Total()))
+
+	savePath := path.Join(tool.RESULT, "Histogram-"+il.GetFileName()+".png")
+	err := tool.SaveHistogramPng(savePath, hist)
 	if err != nil {
 		fmt.Println(err.Error())
 		return
@@ -466,6 +788,1481 @@ func (app App)dealWithAdjBrit() {
 	fmt.Println()
 }
 
+func (app App)dealWithEqualize() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	app.saveResult("Equalize", app.Processor.EqualizeHist(&il))
+}
+
+func (app App)dealWithMatchHistogram() {
+	app.listRaw()
+	fmt.Printf("choose the image & the reference(seperated by space): ")
+	var filename, refName string
+	_, _ = fmt.Scan(&filename, &refName)
+	if isValid1, isValid2 := app.checkRawChoice(filename), app.checkRawChoice(refName); !isValid1 || !isValid2 {
+		fmt.Println("inValid input!")
+		return
+	}
+
+	il, err := tool.NewImgLoader(path.Join(tool.RAW, filename))
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	ref, err := tool.NewImgLoader(path.Join(tool.RAW, refName))
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("MatchHist", app.Processor.MatchHistogram(&il, &ref))
+}
+
+func (app App)dealWithCLAHE() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input tile size & clip limit, separated by space(e.g. 64 2.0): ")
+	var tileSize int
+	var clipLimit float64
+	_, err := fmt.Scan(&tileSize, &clipLimit)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("CLAHE", app.Processor.CLAHE(&il, tileSize, clipLimit))
+}
+
+func (app App)dealWithContrast() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input contrast factor in [0, 4] & pivot in [0, 255], separated by space(e.g. 1.3 128): ")
+	var factor, pivot float64
+	_, err := fmt.Scan(&factor, &pivot)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("Contrast", app.Processor.AdjustContrast(&il, factor, pivot))
+}
+
+func (app App)dealWithGamma() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input gamma in [0.1, 10](greater than 1 brightens): ")
+	var gamma float64
+	_, err := fmt.Scan(&gamma)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("Gamma", app.Processor.AdjustGamma(&il, gamma))
+}
+
+func (app App)dealWithExposure() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input exposure in stops, range [-10, 10]: ")
+	var stops float64
+	_, err := fmt.Scan(&stops)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("Exposure", app.Processor.AdjustExposure(&il, stops))
+}
+
+func (app App)dealWithAutoLevels() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input clip percentage of each end, range [0, 10](e.g. 0.5): ")
+	var clip float64
+	_, err := fmt.Scan(&clip)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("AutoLevels", app.Processor.AutoLevels(&il, clip))
+}
+
+func (app App)dealWithLevels() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input channel(rgb, r, g, b): ")
+	var name string
+	_, _ = fmt.Scan(&name)
+	ch, err := tool.ParseChannel(name)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Print("input black point, white point, gamma, output black & output white, separated by space(e.g. 10 240 1.2 0 255): ")
+	var inBlack, inWhite, outBlack, outWhite uint8
+	var gamma float64
+	_, err = fmt.Scan(&inBlack, &inWhite, &gamma, &outBlack, &outWhite)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	levels := tool.Levels{InBlack: inBlack, InWhite: inWhite, Gamma: gamma, OutBlack: outBlack, OutWhite: outWhite}
+	app.saveResult("Levels", app.Processor.ApplyLevels(&il, ch, levels))
+}
+
+func (app App)dealWithCurves() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	files, err := ioutil.ReadDir(tool.RAW)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	fmt.Println("choice a curve preset:")
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".curves") {
+			fmt.Printf("%s\t", file.Name())
+		}
+	}
+	fmt.Println()
+
+	filename := app.getChoice()
+	preset, err := tool.LoadCurvePreset(path.Join(tool.RAW, filename))
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("Curves", app.Processor.ApplyCurvePreset(&il, preset))
+}
+
+func (app App)dealWithApplyLUT() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	files, err := ioutil.ReadDir(tool.RAW)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	fmt.Println("choice a .cube LUT:")
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".cube") {
+			fmt.Printf("%s\t", file.Name())
+		}
+	}
+	fmt.Println()
+
+	filename := app.getChoice()
+	lut, err := tool.LoadCubeLUT(path.Join(tool.RAW, filename))
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Print("input interpolation(trilinear, tetrahedral): ")
+	var name string
+	_, _ = fmt.Scan(&name)
+	interp := tool.LUTTrilinear
+	if strings.ToLower(name) == "tetrahedral" {
+		interp = tool.LUTTetrahedral
+	}
+
+	app.saveResult("LUT", app.Processor.ApplyLUT(&il, lut, interp))
+}
+
+func (app App)dealWithExportLUT() {
+	fmt.Println("color operations: sunset, negativefilm, gray, brightness=N, contrast=N, gamma=N, exposure=N")
+	fmt.Print("input operations in order, separated by comma(e.g. sunset,contrast=1.2): ")
+	var chain string
+	_, _ = fmt.Scan(&chain)
+
+	var ops []func(*tool.ImgLoader) *tool.ImgLoader
+	for _, item := range strings.Split(chain, ",") {
+		op, err := app.colorOp(item)
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		ops = append(ops, op)
+	}
+
+	fmt.Print("input LUT size in [2, 65](e.g. 33): ")
+	var size int
+	_, err := fmt.Scan(&size)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	if size < 2 || size > 65 {
+		fmt.Println("size has to be in [2, 65]!")
+		return
+	}
+
+	title := strings.Replace(chain, ",", "+", -1)
+	err = tool.SaveCubeLUT(path.Join(tool.RESULT, title+".cube"), app.Processor.BakeCubeLUT(title, size, ops...))
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Println("Succeed, enjoy it")
+	fmt.Println()
+}
+
+func (app App)dealWithHueRotate() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input hue rotation in degrees: ")
+	var degrees float64
+	_, err := fmt.Scan(&degrees)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("HueRotate", app.Processor.HueRotate(&il, degrees))
+}
+
+func (app App)dealWithSaturation() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input saturation in [-1, 1](-1 removes all color): ")
+	var amount float64
+	_, err := fmt.Scan(&amount)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("Saturation", app.Processor.AdjustSaturation(&il, amount))
+}
+
+func (app App)dealWithVibrance() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input vibrance in [-1, 1]: ")
+	var amount float64
+	_, err := fmt.Scan(&amount)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("Vibrance", app.Processor.AdjustVibrance(&il, amount))
+}
+
+func (app App)dealWithSelectiveColor() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input hue center, width & feather in degrees, separated by space(e.g. 120 60 30 for greens): ")
+	var r tool.HueRange
+	_, err := fmt.Scan(&r.Center, &r.Width, &r.Feather)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Print("input hue shift, saturation & lightness in [-1, 1], separated by space(e.g. -20 0.3 0): ")
+	var adj tool.SelectiveAdjust
+	_, err = fmt.Scan(&adj.Hue, &adj.Saturation, &adj.Lightness)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("SelectiveColor", app.Processor.SelectiveColor(&il, r, adj))
+}
+
+func (app App)dealWithWhiteBalance() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input method(grayworld, whitepatch, percentile, temperature, neutral): ")
+	var method string
+	_, _ = fmt.Scan(&method)
+
+	var result *tool.ImgLoader
+	switch strings.ToLower(method) {
+	case "grayworld":
+		result = app.Processor.GrayWorldWB(&il)
+	case "whitepatch":
+		result = app.Processor.WhitePatchWB(&il)
+	case "percentile":
+		fmt.Print("input percentile in [50, 100](e.g. 99): ")
+		var percentile float64
+		_, err := fmt.Scan(&percentile)
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		result = app.Processor.PercentileWB(&il, percentile)
+	case "temperature":
+		fmt.Print("input light source temperature in Kelvin & tint in [-1, 1], separated by space(e.g. 3200 0): ")
+		var kelvin, tint float64
+		_, err := fmt.Scan(&kelvin, &tint)
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		result = app.Processor.TemperatureWB(&il, kelvin, tint)
+	case "neutral":
+		fmt.Print("input x, y of a neutral pixel & sample radius, separated by space: ")
+		var x, y, radius int
+		_, err := fmt.Scan(&x, &y, &radius)
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		result, err = app.Processor.NeutralPickWB(&il, x, y, radius)
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+	default:
+		fmt.Printf("Error, invalid method: %s\n", method)
+		return
+	}
+
+	app.saveResult("WhiteBalance", result)
+}
+
+func (app App)dealWithSepia() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input intensity in [0, 1]: ")
+	var intensity float64
+	_, err := fmt.Scan(&intensity)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("Sepia", app.Processor.Sepia(&il, intensity))
+}
+
+func (app App)dealWithVintage() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input fade in [0, 1]: ")
+	var fade float64
+	_, err := fmt.Scan(&fade)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("Vintage", app.Processor.Vintage(&il, fade))
+}
+
+func (app App)dealWithCrossProcess() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	app.saveResult("CrossProcess", app.Processor.CrossProcess(&il))
+}
+
+func (app App)dealWithDuotone() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input shadow & highlight colors in hex, separated by space(e.g. 1e145a ffc878): ")
+	var darkHex, lightHex string
+	_, _ = fmt.Scan(&darkHex, &lightHex)
+	dark, err := tool.ParseHexColor(darkHex)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	light, err := tool.ParseHexColor(lightHex)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("Duotone", app.Processor.Duotone(&il, dark, light))
+}
+
+func (app App)dealWithSolarize() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input threshold in [0, 255]: ")
+	var threshold uint8
+	_, err := fmt.Scan(&threshold)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("Solarize", app.Processor.Solarize(&il, threshold))
+}
+
+func (app App)dealWithPosterize() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input levels per channel in [2, 64]: ")
+	var levels int
+	_, err := fmt.Scan(&levels)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("Posterize", app.Processor.Posterize(&il, levels))
+}
+
+func (app App)dealWithEmboss() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input strength in [0.1, 5]: ")
+	var strength float64
+	_, err := fmt.Scan(&strength)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("Emboss", app.Processor.Emboss(&il, strength))
+}
+
+func (app App)dealWithVignette() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input strength in [0, 1] & radius in [0, 0.9], separated by space(e.g. 0.8 0.4): ")
+	var strength, radius float64
+	_, err := fmt.Scan(&strength, &radius)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("Vignette", app.Processor.Vignette(&il, strength, radius))
+}
+
+func (app App)dealWithContactSheet() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	filters := app.Processor.DefaultFilters()
+	fmt.Print("filters from left to right, top to bottom:")
+	for _, filter := range filters {
+		fmt.Print(" ", filter.Name)
+	}
+	fmt.Println()
+
+	app.saveResult("ContactSheet", app.Processor.ContactSheet(&il, filters, 240, 4))
+}
+
+func (app App)dealWithOrientation() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input operation(cw90, ccw90, 180, 270, fliph, flipv, transpose, transverse): ")
+	var op string
+	_, _ = fmt.Scan(&op)
+
+	var result *tool.ImgLoader
+	switch strings.ToLower(op) {
+	case "cw90":
+		result = app.Processor.Rotate90(&il)
+	case "ccw90", "270":
+		result = app.Processor.Rotate270(&il)
+	case "180":
+		result = app.Processor.Rotate180(&il)
+	case "fliph":
+		result = app.Processor.FlipHorizontal(&il)
+	case "flipv":
+		result = app.Processor.FlipVertical(&il)
+	case "transpose":
+		result = app.Processor.Transpose(&il)
+	case "transverse":
+		result = app.Processor.Transverse(&il)
+	default:
+		fmt.Printf("Error, invalid operation: %s\n", op)
+		return
+	}
+
+	app.saveResult("Orientation", result)
+}
+
+func (app App)dealWithRotateAngle() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input clockwise degrees: ")
+	var degrees float64
+	_, err := fmt.Scan(&degrees)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	interp, ok := app.getInterpolation()
+	if !ok {
+		return
+	}
+
+	bg, ok := app.getFillColor()
+	if !ok {
+		return
+	}
+
+	fmt.Print("expand canvas to fit the whole image? (y/n): ")
+	var expand string
+	_, _ = fmt.Scan(&expand)
+
+	app.saveResult("RotateAngle", app.Processor.RotateAngle(&il, degrees, interp, bg, strings.ToLower(expand) == "y"))
+}
+
+func (app App)dealWithThumbnail() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input sizes as WxH, separated by comma(e.g. 64x64,128x96,256x256): ")
+	var sizes string
+	_, _ = fmt.Scan(&sizes)
+
+	fmt.Print("input mode(fit, fill, pad, smart): ")
+	var name string
+	_, _ = fmt.Scan(&name)
+	mode, err := tool.ParseThumbnailMode(name)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	bg := color.RGBA{}
+	if mode == tool.ThumbPad {
+		if bg, ok = app.getFillColor(); !ok {
+			return
+		}
+	}
+
+	for _, size := range strings.Split(sizes, ",") {
+		var width, height int
+		_, err = fmt.Sscanf(size, "%dx%d", &width, &height)
+		if err != nil || width <= 0 || height <= 0 {
+			fmt.Printf("Error, invalid size: %s\n", size)
+			continue
+		}
+
+		app.saveResult(fmt.Sprintf("Thumb-%s-%dx%d", mode, width, height), app.Processor.Thumbnail(&il, width, height, mode, bg))
+	}
+}
+
+func (app App)dealWithCrop() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input mode(rect, aspect, trim): ")
+	var mode string
+	_, _ = fmt.Scan(&mode)
+
+	var result *tool.ImgLoader
+	var err error
+	switch strings.ToLower(mode) {
+	case "rect":
+		fmt.Print("input x, y, width & height, separated by space: ")
+		var x, y, w, h int
+		if _, err = fmt.Scan(&x, &y, &w, &h); err == nil {
+			result, err = app.Processor.Crop(&il, x, y, w, h)
+		}
+	case "aspect":
+		fmt.Print("input aspect ratio as W:H(e.g. 16:9): ")
+		var ratio string
+		_, _ = fmt.Scan(&ratio)
+		var rw, rh float64
+		if _, err = fmt.Sscanf(ratio, "%g:%g", &rw, &rh); err == nil {
+			result, err = app.Processor.CropToAspect(&il, rw, rh)
+		}
+	case "trim":
+		fmt.Print("input tolerance in [0, 255]: ")
+		var tolerance uint8
+		if _, err = fmt.Scan(&tolerance); err == nil {
+			result = app.Processor.AutoTrim(&il, tolerance)
+		}
+	default:
+		fmt.Printf("Error, invalid mode: %s\n", mode)
+		return
+	}
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("Crop", result)
+}
+
+func (app App)dealWithPad() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input top, right, bottom & left padding, separated by space: ")
+	var top, right, bottom, left int
+	_, err := fmt.Scan(&top, &right, &bottom, &left)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Print("input mode(color, replicate, reflect): ")
+	var name string
+	_, _ = fmt.Scan(&name)
+
+	mode, bg := tool.PadColor, color.RGBA{}
+	switch strings.ToLower(name) {
+	case "color":
+		if bg, ok = app.getFillColor(); !ok {
+			return
+		}
+	case "replicate":
+		mode = tool.PadReplicate
+	case "reflect":
+		mode = tool.PadReflect
+	default:
+		fmt.Printf("Error, invalid mode: %s\n", name)
+		return
+	}
+
+	app.saveResult("Pad", app.Processor.Pad(&il, top, right, bottom, left, mode, bg))
+}
+
+func (app App)dealWithCanvas() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Printf("current size: %dx%d, input new width & height, separated by space: ", il.GetMX(), il.GetMY())
+	var width, height int
+	_, err := fmt.Scan(&width, &height)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	anchor, ok := app.getAnchor()
+	if !ok {
+		return
+	}
+
+	bg, ok := app.getFillColor()
+	if !ok {
+		return
+	}
+
+	app.saveResult("Canvas", app.Processor.ResizeCanvas(&il, width, height, anchor, bg))
+}
+
+func (app App)dealWithROI() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input ROI x, y, width & height, separated by space: ")
+	var x, y, w, h int
+	_, err := fmt.Scan(&x, &y, &w, &h)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	filters := app.Processor.DefaultFilters()
+	fmt.Print("filters:")
+	for _, filter := range filters {
+		fmt.Print(" ", filter.Name)
+	}
+	fmt.Println()
+	name := app.getChoice()
+
+	for _, filter := range filters {
+		if strings.ToLower(filter.Name) != strings.ToLower(name) {
+			continue
+		}
+
+		result, err := app.Processor.ApplyToROI(&il, image.Rect(x, y, x+w, y+h), filter.Apply)
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		app.saveResult("ROI", result)
+		return
+	}
+
+	fmt.Printf("Error, invalid filter: %s\n", name)
+}
+
+func (app App)dealWithAffine() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input affine matrix a b c d e f, separated by space(x' = ax + by + c, y' = dx + ey + f): ")
+	var m tool.Affine
+	_, err := fmt.Scan(&m[0], &m[1], &m[2], &m[3], &m[4], &m[5])
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Print("input output width & height, separated by space(0 0 to fit the whole image): ")
+	var width, height int
+	_, err = fmt.Scan(&width, &height)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	interp, ok := app.getInterpolation()
+	if !ok {
+		return
+	}
+
+	bg, ok := app.getFillColor()
+	if !ok {
+		return
+	}
+
+	result, err := app.Processor.WarpAffine(&il, m, width, height, interp, bg)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("Affine", result)
+}
+
+func (app App)dealWithPerspective() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input 4 corners of the region as x y, top-left, top-right, bottom-right, bottom-left, separated by space: ")
+	var quad [4]tool.Point
+	for i := range quad {
+		_, err := fmt.Scan(&quad[i].X, &quad[i].Y)
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+	}
+
+	fmt.Print("input output width & height, separated by space: ")
+	var width, height int
+	_, err := fmt.Scan(&width, &height)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	interp, ok := app.getInterpolation()
+	if !ok {
+		return
+	}
+
+	result, err := app.Processor.Deskew(&il, quad, width, height, interp, color.RGBA{A: 255})
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("Perspective", result)
+}
+
+func (app App)dealWithSeamCarve() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Printf("input target width & height, separated by space(now %d x %d): ", il.GetMX(), il.GetMY())
+	var width, height int
+	_, err := fmt.Scan(&width, &height)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	mask, ok := app.getSeamMask(false)
+	if !ok {
+		return
+	}
+
+	result, err := app.Processor.SeamCarve(&il, width, height, mask)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("SeamCarve", result)
+}
+
+func (app App)dealWithRemoveObject() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	mask, ok := app.getSeamMask(true)
+	if !ok {
+		return
+	}
+
+	result, err := app.Processor.RemoveObject(&il, mask)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("RemoveObject", result)
+}
+
+// 读取与原图同尺寸的标记图作为缝裁剪掩码，绿色保护、红色删除，非必需时输入 none 跳过
+func (app App)getSeamMask(required bool) (tool.SeamMask, bool) {
+	if required {
+		fmt.Print("input mask image in raw(green to protect, red to remove): ")
+	} else {
+		fmt.Print("input mask image in raw(green to protect, red to remove), or none: ")
+	}
+	var filename string
+	_, _ = fmt.Scan(&filename)
+	if !required && strings.ToLower(filename) == "none" {
+		return nil, true
+	}
+	if isValid := app.checkRawChoice(filename); !isValid {
+		fmt.Println("inValid input!")
+		return nil, false
+	}
+
+	il, err := tool.NewImgLoader(path.Join(tool.RAW, filename))
+	if err != nil {
+		fmt.Println(err.Error())
+		return nil, false
+	}
+
+	return tool.MaskFromImage(&il), true
+}
+
+func (app App)dealWithWatermark() {
+	app.listRaw()
+	fmt.Print("choose the image(or all for every raw image) & the watermark, separated by space: ")
+	var filename, markName string
+	_, _ = fmt.Scan(&filename, &markName)
+	batch := strings.ToLower(filename) == "all"
+	if isValid1, isValid2 := batch || app.checkRawChoice(filename), app.checkRawChoice(markName); !isValid1 || !isValid2 {
+		fmt.Println("inValid input!")
+		return
+	}
+
+	mark, err := tool.NewImgLoader(path.Join(tool.RAW, markName))
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	opt := tool.DefaultWatermarkOptions()
+	anchor, ok := app.getAnchor()
+	if !ok {
+		return
+	}
+	opt.Anchor = anchor
+
+	fmt.Print("input offset x y, margin, scale(0 to keep size) & opacity, separated by space(e.g. 0 0 16 0.2 0.6): ")
+	_, err = fmt.Scan(&opt.OffsetX, &opt.OffsetY, &opt.Margin, &opt.Scale, &opt.Opacity)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Printf("input tile mode(%s): ", strings.Join(tool.TileModeNames(), ", "))
+	var name string
+	_, _ = fmt.Scan(&name)
+	opt.Tile, err = tool.ParseTileMode(name)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	if batch {
+		count, failed, err := app.Processor.WatermarkDir(tool.RAW, tool.RESULT, &mark, opt)
+		names := make([]string, 0, len(failed))
+		for name := range failed {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("%s: %s\n", name, failed[name].Error())
+		}
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		fmt.Printf("%d images watermarked, %d failed to decode\n", count, len(failed))
+		fmt.Println("Succeed, enjoy it")
+		fmt.Println()
+		return
+	}
+
+	il, err := tool.NewImgLoader(path.Join(tool.RAW, filename))
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("watermark", app.Processor.Watermark(&il, &mark, opt))
+}
+
+func (app App)dealWithText() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	style := tool.DefaultTextStyle()
+	fmt.Print("input font file(TrueType/OpenType, path relative to raw or absolute), or default for the built-in bitmap font: ")
+	var fontFile string
+	_, _ = fmt.Scan(&fontFile)
+
+	fmt.Print("input font size(px) & text color in hex(rrggbb or rrggbbaa), separated by space(e.g. 32 ffffffcc): ")
+	var hex string
+	_, err := fmt.Scan(&style.Size, &hex)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	if style.Color, err = tool.ParseHexColor(hex); err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	if strings.ToLower(fontFile) != "default" {
+		if !path.IsAbs(fontFile) {
+			fontFile = path.Join(tool.RAW, fontFile)
+		}
+		style.Font, err = tool.LoadFont(fontFile)
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+	}
+
+	fmt.Print("input stroke width & stroke color, separated by space(e.g. 2 000000, 0 to disable): ")
+	_, err = fmt.Scan(&style.StrokeWidth, &hex)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	if style.StrokeColor, err = tool.ParseHexColor(hex); err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Print("input shadow offset x y & shadow color, separated by space(e.g. 3 3 00000080, 0 0 to disable): ")
+	_, err = fmt.Scan(&style.ShadowX, &style.ShadowY, &hex)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	if style.ShadowColor, err = tool.ParseHexColor(hex); err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Printf("input align(%s): ", strings.Join(tool.TextAlignNames(), ", "))
+	var name string
+	_, _ = fmt.Scan(&name)
+	if style.Align, err = tool.ParseTextAlign(name); err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Print("input text box x, y, width & height, separated by space(0 0 0 0 for the whole image): ")
+	var x, y, w, h int
+	_, err = fmt.Scan(&x, &y, &w, &h)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Print(`input text, \n for a new line, {time} for the current time: `)
+	text := strings.Replace(app.readLine(), `\n`, "\n", -1)
+	text = strings.Replace(text, "{time}", time.Now().Format("2006-01-02 15:04:05"), -1)
+	if strings.TrimSpace(text) == "" {
+		fmt.Println("inValid input!")
+		return
+	}
+
+	app.saveResult("Text", app.Processor.DrawText(&il, text, image.Rect(x, y, x+w, y+h), style))
+}
+
+func (app App)dealWithDraw() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	files, err := ioutil.ReadDir(tool.RAW)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	fmt.Println("choice a .draw command file:")
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".draw") {
+			fmt.Printf("%s\t", file.Name())
+		}
+	}
+	fmt.Println()
+
+	filename := app.getChoice()
+	commands, err := tool.LoadDrawCommands(path.Join(tool.RAW, filename))
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("Draw", app.Processor.ApplyDrawCommands(&il, commands))
+}
+
+func (app App)dealWithAlpha() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	fmt.Print("input mode(extract, set, premultiply, unpremultiply, flatten, colortoalpha, feather): ")
+	var mode string
+	_, _ = fmt.Scan(&mode)
+
+	var result *tool.ImgLoader
+	var err error
+	switch strings.ToLower(mode) {
+	case "extract":
+		result = app.Processor.ExtractAlpha(&il)
+	case "set":
+		fmt.Print("input grayscale mask image in raw: ")
+		var filename string
+		_, _ = fmt.Scan(&filename)
+		if isValid := app.checkRawChoice(filename); !isValid {
+			fmt.Println("inValid input!")
+			return
+		}
+		var mask tool.ImgLoader
+		if mask, err = tool.NewImgLoader(path.Join(tool.RAW, filename)); err == nil {
+			result = app.Processor.SetAlpha(&il, &mask)
+		}
+	case "premultiply":
+		result = app.Processor.Premultiply(&il)
+	case "unpremultiply":
+		result = app.Processor.Unpremultiply(&il)
+	case "flatten":
+		var bg color.RGBA
+		if bg, ok = app.getFillColor(); !ok {
+			return
+		}
+		result = app.Processor.Flatten(&il, bg)
+	case "colortoalpha":
+		fmt.Print("input color in hex, tolerance & softness in [0, 255], separated by space(e.g. ffffff 10 20): ")
+		var hex string
+		var tolerance, softness float64
+		if _, err = fmt.Scan(&hex, &tolerance, &softness); err == nil {
+			var c color.RGBA
+			if c, err = tool.ParseHexColor(hex); err == nil {
+				result = app.Processor.ColorToAlpha(&il, c, tolerance, softness)
+			}
+		}
+	case "feather":
+		fmt.Print("input feather radius(gaussian sigma): ")
+		var sigma float64
+		if _, err = fmt.Scan(&sigma); err == nil {
+			result = app.Processor.FeatherAlpha(&il, sigma)
+		}
+	default:
+		fmt.Printf("Error, invalid mode: %s\n", mode)
+		return
+	}
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("Alpha", result)
+}
+
+func (app App)dealWithChromaKey() {
+	il, ok := app.chooseRawImg()
+	if !ok {
+		return
+	}
+
+	opt := tool.DefaultChromaKeyOptions()
+	fmt.Print("input key color in hex, tolerance, softness & spill suppression in [0, 1], separated by space(e.g. 00ff00 40 30 0.8): ")
+	var hex string
+	_, err := fmt.Scan(&hex, &opt.Tolerance, &opt.Softness, &opt.Spill)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	if opt.Key, err = tool.ParseHexColor(hex); err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Print("input background image in raw, or none for a transparent result: ")
+	var filename string
+	_, _ = fmt.Scan(&filename)
+	if strings.ToLower(filename) == "none" {
+		app.saveResult("ChromaKey", app.Processor.ChromaKey(&il, opt))
+		return
+	}
+	if isValid := app.checkRawChoice(filename); !isValid {
+		fmt.Println("inValid input!")
+		return
+	}
+
+	bg, err := tool.NewImgLoader(path.Join(tool.RAW, filename))
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	app.saveResult("ChromaKey", app.Processor.ChromaKeyOver(&il, &bg, opt))
+}
+
+func (app App)getAnchor() (tool.Anchor, bool) {
+	fmt.Printf("input anchor(%s): ", strings.Join(tool.AnchorNames(), ", "))
+	var name string
+	_, _ = fmt.Scan(&name)
+
+	anchor, err := tool.ParseAnchor(name)
+	if err != nil {
+		fmt.Println(err.Error())
+		return anchor, false
+	}
+
+	return anchor, true
+}
+
+func (app App)getInterpolation() (tool.Interpolation, bool) {
+	fmt.Print("input interpolation(nearest, bilinear, bicubic): ")
+	var name string
+	_, _ = fmt.Scan(&name)
+
+	switch strings.ToLower(name) {
+	case "nearest":
+		return tool.InterpNearest, true
+	case "bilinear":
+		return tool.InterpBilinear, true
+	case "bicubic":
+		return tool.InterpBicubic, true
+	}
+
+	fmt.Printf("Error, invalid interpolation: %s\n", name)
+	return tool.InterpBilinear, false
+}
+
+// 读取十六进制背景色，"transparent" 表示透明
+func (app App)getFillColor() (color.RGBA, bool) {
+	fmt.Print("input background color in hex or \"transparent\"(e.g. ffffff): ")
+	var hex string
+	_, _ = fmt.Scan(&hex)
+	if strings.ToLower(hex) == "transparent" {
+		return color.RGBA{}, true
+	}
+
+	bg, err := tool.ParseHexColor(hex)
+	if err != nil {
+		fmt.Println(err.Error())
+		return bg, false
+	}
+
+	return bg, true
+}
+
+// 解析 "名称" 或 "名称=参数" 形式的逐像素颜色操作
+func (app App)colorOp(item string) (func(*tool.ImgLoader) *tool.ImgLoader, error) {
+	parts := strings.SplitN(strings.ToLower(strings.TrimSpace(item)), "=", 2)
+	var param float64
+	if len(parts) == 2 {
+		var err error
+		param, err = strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameter: %s", item)
+		}
+	}
+
+	ip := app.Processor
+	switch parts[0] {
+	case "sunset":
+		return ip.SunsetEffect, nil
+	case "negativefilm":
+		return ip.NegativeFilmEffect, nil
+	case "gray":
+		return ip.RGB2Gray, nil
+	case "brightness":
+		return func(il *tool.ImgLoader) *tool.ImgLoader { return ip.AdjustBrightness(il, param) }, nil
+	case "contrast":
+		return func(il *tool.ImgLoader) *tool.ImgLoader { return ip.AdjustContrast(il, param, 128) }, nil
+	case "gamma":
+		return func(il *tool.ImgLoader) *tool.ImgLoader { return ip.AdjustGamma(il, param) }, nil
+	case "exposure":
+		return func(il *tool.ImgLoader) *tool.ImgLoader { return ip.AdjustExposure(il, param) }, nil
+	}
+
+	return nil, fmt.Errorf("unknown color operation: %s", item)
+}
+
+// 列出 raw 目录下的图片，读取用户的选择并加载
+func (app App)chooseRawImg() (tool.ImgLoader, bool) {
+	app.listRaw()
+	filename := app.getChoice()
+	if isValid := app.checkRawChoice(filename); !isValid {
+		fmt.Println("inValid input!")
+		return tool.ImgLoader{}, false
+	}
+
+	il, err := tool.NewImgLoader(path.Join(tool.RAW, filename))
+	if err != nil {
+		fmt.Println(err.Error())
+		return tool.ImgLoader{}, false
+	}
+
+	return il, true
+}
+
+// 以 "前缀-文件名.png" 保存处理结果
+func (app App)saveResult(prefix string, il *tool.ImgLoader) {
+	savePath := path.Join(tool.RESULT, prefix+"-"+il.GetFileName()+".png")
+	err := tool.SaveAsPng(savePath, il.GetMatrix())
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Println("Succeed, enjoy it")
+	fmt.Println()
+}
+
+// 与 saveResult 相同，但以 1 bit 深度保存
+func (app App)saveBinary(prefix string, il *tool.ImgLoader) {
+	savePath := path.Join(tool.RESULT, prefix+"-"+il.GetFileName()+".png")
+	err := tool.SaveAsBinaryPng(savePath, il.GetMatrix())
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Println("Succeed, enjoy it")
+	fmt.Println()
+}
+
+// 去重报告
+type DedupeReport struct {
+	Dir       string                `json:"dir"`
+	Algorithm string                `json:"algorithm"`
+	Distance  int                   `json:"distance"`
+	Indexed   int                   `json:"indexed"`
+	Groups    []tool.DuplicateGroup `json:"groups"`
+}
+
+// 增量更新 dir 的指纹索引并保存，再找出距离不超过 distance 的近似重复图片
+func dedupe(dir, indexFile, algorithm string, size, distance int) (DedupeReport, error) {
+	idx, err := tool.LoadImageIndex(indexFile)
+	if os.IsNotExist(err) {
+		idx, err = tool.NewImageIndex(algorithm, size)
+	}
+	if err != nil {
+		return DedupeReport{}, err
+	}
+	if idx.Algorithm != strings.ToLower(algorithm) || idx.HashSize != size {
+		return DedupeReport{}, fmt.Errorf("%s was built with %s(size %d), use another index file", indexFile, idx.Algorithm, idx.HashSize)
+	}
+
+	stats, err := idx.Update(dir)
+	if err != nil {
+		return DedupeReport{}, err
+	}
+	fmt.Fprintf(os.Stderr, "index updated: %d added, %d updated, %d removed, %d unreadable skipped, %d failed to decode\n", stats.Added, stats.Updated, stats.Removed, stats.Skipped, stats.Failed)
+	if err = idx.Save(indexFile); err != nil {
+		return DedupeReport{}, err
+	}
+
+	groups := idx.Duplicates(dir, distance)
+	if groups == nil {
+		groups = []tool.DuplicateGroup{}
+	}
+
+	return DedupeReport{
+		Dir: dir,
+		Algorithm: idx.Algorithm,
+		Distance: distance,
+		Indexed: len(idx.Paths(dir)),
+		Groups: groups,
+	}, nil
+}
+
+func dedupeCommand(args []string) int {
+	flags := flag.NewFlagSet("dedupe", flag.ContinueOnError)
+	dir := flags.String("dir", tool.RAW, "directory tree to scan")
+	indexFile := flags.String("index", "imgproc.index", "fingerprint index file, updated incrementally")
+	algorithm := flags.String("algo", "dhash", "hash algorithm: "+strings.Join(tool.HasherNames(), ", "))
+	size := flags.Int("size", 8, "hash size")
+	distance := flags.Int("distance", 6, "max hamming distance between duplicates")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	report, err := dedupe(*dir, *indexFile, *algorithm, *size, *distance)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+
+	data, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(data))
+
+	return 0
+}
+
+func (app App)dealWithDedupe() {
+	fmt.Print("input max hamming distance between duplicates(dhash, 64 bits, e.g. 6): ")
+	var distance int
+	_, err := fmt.Scan(&distance)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	report, err := dedupe(tool.RAW, path.Join(tool.RESULT, "raw.index"), "dhash", 8, distance)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	data, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(data))
+	fmt.Println()
+}
+
+func (app App)dealWithQuality() {
+	app.listRaw()
+	fmt.Printf("choose the reference image and the image to evaluate(seperated by space): ")
+	var filename1, filename2 string
+	_, _ = fmt.Scan(&filename1, &filename2)
+	if isValid1, isValid2 := app.checkRawChoice(filename1), app.checkRawChoice(filename2); !isValid1 || !isValid2 {
+		fmt.Println("inValid input!")
+		return
+	}
+
+	fmt.Print("resize the second image if the sizes differ? (y/n): ")
+	var resize string
+	_, _ = fmt.Scan(&resize)
+
+	ref, err := tool.NewImgLoader(path.Join(tool.RAW, filename1))
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	il, err := tool.NewImgLoader(path.Join(tool.RAW, filename2))
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	report, ssimMap, err := app.Processor.Quality(&ref, &il, strings.ToLower(resize) == "y")
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Printf("MSE: %.4f\n", report.MSE)
+	fmt.Printf("PSNR: %.2f dB\n", report.PSNR)
+	fmt.Printf("SSIM: %.4f\n", report.SSIM)
+	fmt.Printf("MS-SSIM: %.4f\n", report.MSSSIM)
+	app.saveResult("SSIM", ssimMap)
+}
+
 func loadPics() ([]string, error) {
 	dir ,err := ioutil.ReadDir(tool.RAW)
 	if err != nil {
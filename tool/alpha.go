@@ -0,0 +1,147 @@
+package tool
+
+import (
+	"image/color"
+	"math"
+)
+
+// 把透明度通道取出为不透明的灰度蒙版，白色为不透明
+func (ip *ImgProcessor)ExtractAlpha(il *ImgLoader) *ImgLoader {
+	return mapRGBA(il, func(p []uint8) {
+		p[0], p[1], p[2], p[3] = p[3], p[3], p[3], 255
+	})
+}
+
+// 以灰度蒙版的亮度作为透明度，蒙版会缩放到与原图相同的大小
+func (ip *ImgProcessor)SetAlpha(il, mask *ImgLoader) *ImgLoader {
+	if mask.GetMX() != il.GetMX() || mask.GetMY() != il.GetMY() {
+		mask = ip.Resize(mask, il.GetMY(), il.GetMX())
+	}
+	alpha := grayPlane(mask.GetMatrix())
+
+	imgMatrix := il.GetMatrix()
+	for hi := range imgMatrix {
+		for wi := range imgMatrix[hi] {
+			imgMatrix[hi][wi][3] = clampUint8(alpha[hi][wi])
+		}
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: imgMatrix,
+		img: il.GetImg(),
+	}
+}
+
+// 颜色乘以透明度
+func (ip *ImgProcessor)Premultiply(il *ImgLoader) *ImgLoader {
+	return mapRGBA(il, func(p []uint8) {
+		for c := 0; c < 3; c++ {
+			p[c] = uint8((int(p[c])*int(p[3]) + 127) / 255)
+		}
+	})
+}
+
+// Premultiply 的逆运算，透明度为 0 的像素颜色置 0
+func (ip *ImgProcessor)Unpremultiply(il *ImgLoader) *ImgLoader {
+	return mapRGBA(il, func(p []uint8) {
+		for c := 0; c < 3; c++ {
+			if p[3] == 0 {
+				p[c] = 0
+			} else {
+				p[c] = clampUint8(float64(p[c]) * 255 / float64(p[3]))
+			}
+		}
+	})
+}
+
+// 合成到纯色背景上，bg 的透明度被忽略，结果完全不透明
+func (ip *ImgProcessor)Flatten(il *ImgLoader, bg color.RGBA) *ImgLoader {
+	return mapRGBA(il, func(p []uint8) {
+		dst := []uint8{bg.R, bg.G, bg.B, 255}
+		blendPixel(dst, p, BlendNormal, 1)
+		copy(p, dst)
+	})
+}
+
+// 把与 c 相近的颜色变为透明：各通道最大差值不超过 tolerance 的像素完全透明，
+// 差值在 (tolerance, tolerance+softness) 之间的像素按距离渐变
+func (ip *ImgProcessor)ColorToAlpha(il *ImgLoader, c color.RGBA, tolerance, softness float64) *ImgLoader {
+	return mapRGBA(il, func(p []uint8) {
+		d := math.Max(math.Abs(float64(p[0])-float64(c.R)), math.Max(math.Abs(float64(p[1])-float64(c.G)), math.Abs(float64(p[2])-float64(c.B))))
+
+		keep := 1.0
+		if d <= tolerance {
+			keep = 0
+		} else if softness > 0 && d < tolerance+softness {
+			keep = (d - tolerance) / softness
+		}
+		p[3] = clampUint8(float64(p[3]) * keep)
+	})
+}
+
+// 对透明度通道做高斯模糊，使蒙版边缘柔和过渡。
+// 原本完全透明、模糊后变得可见的像素没有有效颜色（通常为黑色），
+// 取周围可见像素按透明度加权的平均色，避免合成时边缘出现暗边
+func (ip *ImgProcessor)FeatherAlpha(il *ImgLoader, sigma float64) *ImgLoader {
+	imgMatrix := il.GetMatrix()
+	if sigma <= 0 {
+		return &ImgLoader{
+			filename: il.GetFileName(),
+			format: il.GetFormat(),
+			matrix: imgMatrix,
+			img: il.GetImg(),
+		}
+	}
+
+	// 预乘透明度后的颜色与透明度做同样的模糊，二者相除即为加权平均色
+	alpha := channelPlane(imgMatrix, 3)
+	var premul [3]plane
+	for c := range premul {
+		premul[c] = newPlane(il.GetMY(), il.GetMX())
+		for hi := range imgMatrix {
+			for wi, p := range imgMatrix[hi] {
+				premul[c][hi][wi] = float64(p[c]) * float64(p[3]) / 255
+			}
+		}
+		premul[c] = premul[c].gaussianBlur(sigma)
+	}
+	alpha = alpha.gaussianBlur(sigma)
+
+	for hi := range imgMatrix {
+		for wi, p := range imgMatrix[hi] {
+			a := alpha[hi][wi]
+			if p[3] == 0 && a > 0 {
+				for c := range premul {
+					p[c] = clampUint8(premul[c][hi][wi] * 255 / a)
+				}
+			}
+			p[3] = clampUint8(a)
+		}
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: imgMatrix,
+		img: il.GetImg(),
+	}
+}
+
+// 逐像素就地修改 RGBA，作用于原图的副本
+func mapRGBA(il *ImgLoader, fn func(p []uint8)) *ImgLoader {
+	imgMatrix := il.GetMatrix()
+	for hi := range imgMatrix {
+		for wi := range imgMatrix[hi] {
+			fn(imgMatrix[hi][wi])
+		}
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: imgMatrix,
+		img: il.GetImg(),
+	}
+}
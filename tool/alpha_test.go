@@ -0,0 +1,47 @@
+package tool
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestFeatherAlphaBleedsEdgeColor(t *testing.T) {
+	// 左半部分为不透明红色，右半部分完全透明且颜色为黑色
+	matrix := NewRGBAMatrix(10, 20)
+	for hi := range matrix {
+		for wi := 0; wi < 10; wi++ {
+			matrix[hi][wi][0], matrix[hi][wi][3] = 255, 255
+		}
+	}
+	il := &ImgLoader{matrix: matrix}
+	ip := &ImgProcessor{}
+
+	result := ip.FeatherAlpha(il, 2).GetMatrix()
+	feathered := 0
+	for hi := range result {
+		for wi, p := range result[hi] {
+			if p[3] == 0 {
+				continue
+			}
+			if p[0] != 255 || p[1] != 0 || p[2] != 0 {
+				t.Fatalf("pixel (%d, %d) = %v, want red", wi, hi, p)
+			}
+			if wi >= 10 {
+				feathered++
+			}
+		}
+	}
+	if feathered == 0 {
+		t.Fatal("alpha was not feathered into the transparent half")
+	}
+
+	// 合成到白色背景上，边缘只能介于红与白之间，不能出现暗边
+	flat := ip.Flatten(ip.FeatherAlpha(il, 2), color.RGBA{R: 255, G: 255, B: 255, A: 255}).GetMatrix()
+	for hi := range flat {
+		for wi, p := range flat[hi] {
+			if p[0] != 255 || p[1] != p[2] {
+				t.Fatalf("flattened pixel (%d, %d) = %v, want a red-white mix", wi, hi, p)
+			}
+		}
+	}
+}
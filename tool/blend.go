@@ -0,0 +1,244 @@
+package tool
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// 图层混合模式，公式参照 W3C Compositing and Blending 规范
+type BlendMode int
+
+const (
+	BlendNormal BlendMode = iota
+	BlendMultiply
+	BlendScreen
+	BlendOverlay
+	BlendSoftLight
+	BlendHardLight
+	BlendDarken
+	BlendLighten
+	BlendDifference
+	BlendExclusion
+	BlendColorDodge
+	BlendColorBurn
+	BlendHue
+	BlendSaturation
+	BlendColor
+	BlendLuminosity
+)
+
+var blendModeNames = []string{
+	"normal", "multiply", "screen", "overlay", "softlight", "hardlight", "darken", "lighten",
+	"difference", "exclusion", "colordodge", "colorburn", "hue", "saturation", "color", "luminosity",
+}
+
+func (mode BlendMode)String() string {
+	if mode < BlendNormal || mode > BlendLuminosity {
+		return "unknown"
+	}
+
+	return blendModeNames[mode]
+}
+
+func ParseBlendMode(name string) (BlendMode, error) {
+	for i, v := range blendModeNames {
+		if strings.ToLower(name) == v {
+			return BlendMode(i), nil
+		}
+	}
+
+	return BlendNormal, fmt.Errorf("unknown blend mode: %s", name)
+}
+
+func BlendModeNames() []string {
+	return blendModeNames
+}
+
+// 以 50% 不透明度把 il2 叠加到 il1 上，il2 会缩放到 il1 的大小
+func (ip *ImgProcessor)ImageFusion(il1 *ImgLoader, il2 *ImgLoader) *ImgLoader {
+	return ip.Blend(il1, il2, BlendNormal, 0.5)
+}
+
+// 把 top 按混合模式 mode 与不透明度 opacity（[0, 1]）合成到 base 上，
+// top 会缩放到 base 的大小，透明度按 Porter-Duff source-over 规则合成
+func (ip *ImgProcessor)Blend(base, top *ImgLoader, mode BlendMode, opacity float64) *ImgLoader {
+	imgMatrix := base.GetMatrix()
+	if top.GetMX() != base.GetMX() || top.GetMY() != base.GetMY() {
+		top = ip.Resize(top, base.GetMY(), base.GetMX())
+	}
+	blendMatrix(imgMatrix, top.GetMatrix(), 0, 0, mode, opacity)
+
+	return &ImgLoader{
+		filename: base.GetFileName(),
+		format: base.GetFormat(),
+		matrix: imgMatrix,
+		img: base.GetImg(),
+	}
+}
+
+// 把 src 合成到 dst 的 (x, y) 处，超出 dst 的部分被忽略
+func blendMatrix(dst, src [][][]uint8, x, y int, mode BlendMode, opacity float64) {
+	for hi := range src {
+		dy := y + hi
+		if dy < 0 || dy >= len(dst) {
+			continue
+		}
+		for wi := range src[hi] {
+			dx := x + wi
+			if dx < 0 || dx >= len(dst[dy]) {
+				continue
+			}
+			blendPixel(dst[dy][dx], src[hi][wi], mode, opacity)
+		}
+	}
+}
+
+// 单个像素的合成：
+// co = as*(1-ab)*Cs + as*ab*B(Cb, Cs) + (1-as)*ab*Cb，ao = as + ab*(1-as)
+func blendPixel(dst, src []uint8, mode BlendMode, opacity float64) {
+	as := float64(src[3]) / 255 * math.Max(0, math.Min(1, opacity))
+	if as == 0 {
+		return
+	}
+	ab := float64(dst[3]) / 255
+
+	var cb, cs [3]float64
+	for c := 0; c < 3; c++ {
+		cb[c], cs[c] = float64(dst[c])/255, float64(src[c])/255
+	}
+	mixed := blendColor(cb, cs, mode)
+
+	ao := as + ab*(1-as)
+	for c := 0; c < 3; c++ {
+		co := as*(1-ab)*cs[c] + as*ab*mixed[c] + (1-as)*ab*cb[c]
+		dst[c] = clampUint8(co / ao * 255)
+	}
+	dst[3] = clampUint8(ao * 255)
+}
+
+// 混合函数 B(Cb, Cs)，各分量取值 [0, 1]
+func blendColor(cb, cs [3]float64, mode BlendMode) [3]float64 {
+	switch mode {
+	case BlendHue:
+		return setLum(setSat(cs, sat(cb)), lum(cb))
+	case BlendSaturation:
+		return setLum(setSat(cb, sat(cs)), lum(cb))
+	case BlendColor:
+		return setLum(cs, lum(cb))
+	case BlendLuminosity:
+		return setLum(cb, lum(cs))
+	}
+
+	var result [3]float64
+	for c := range result {
+		result[c] = blendChannel(cb[c], cs[c], mode)
+	}
+
+	return result
+}
+
+// 可分离的混合模式，逐通道计算
+func blendChannel(b, s float64, mode BlendMode) float64 {
+	switch mode {
+	case BlendMultiply:
+		return b * s
+	case BlendScreen:
+		return b + s - b*s
+	case BlendOverlay:
+		return blendChannel(s, b, BlendHardLight)
+	case BlendHardLight:
+		if s <= 0.5 {
+			return b * 2 * s
+		}
+		return blendChannel(b, 2*s-1, BlendScreen)
+	case BlendSoftLight:
+		if s <= 0.5 {
+			return b - (1-2*s)*b*(1-b)
+		}
+		var d float64
+		if b <= 0.25 {
+			d = ((16*b-12)*b + 4) * b
+		} else {
+			d = math.Sqrt(b)
+		}
+		return b + (2*s-1)*(d-b)
+	case BlendDarken:
+		return math.Min(b, s)
+	case BlendLighten:
+		return math.Max(b, s)
+	case BlendDifference:
+		return math.Abs(b - s)
+	case BlendExclusion:
+		return b + s - 2*b*s
+	case BlendColorDodge:
+		if b == 0 {
+			return 0
+		} else if s >= 1 {
+			return 1
+		}
+		return math.Min(1, b/(1-s))
+	case BlendColorBurn:
+		if b >= 1 {
+			return 1
+		} else if s <= 0 {
+			return 0
+		}
+		return 1 - math.Min(1, (1-b)/s)
+	}
+
+	return s
+}
+
+// 非分离混合模式用到的亮度与饱和度辅助函数
+func lum(c [3]float64) float64 {
+	return 0.3*c[0] + 0.59*c[1] + 0.11*c[2]
+}
+
+func clipColor(c [3]float64) [3]float64 {
+	l := lum(c)
+	n := math.Min(c[0], math.Min(c[1], c[2]))
+	x := math.Max(c[0], math.Max(c[1], c[2]))
+	for i := range c {
+		if n < 0 {
+			c[i] = l + (c[i]-l)*l/(l-n)
+		}
+		if x > 1 {
+			c[i] = l + (c[i]-l)*(1-l)/(x-l)
+		}
+	}
+
+	return c
+}
+
+func setLum(c [3]float64, l float64) [3]float64 {
+	d := l - lum(c)
+	return clipColor([3]float64{c[0] + d, c[1] + d, c[2] + d})
+}
+
+func sat(c [3]float64) float64 {
+	return math.Max(c[0], math.Max(c[1], c[2])) - math.Min(c[0], math.Min(c[1], c[2]))
+}
+
+// 保持分量的大小顺序，把饱和度（最大值与最小值之差）设为 s
+func setSat(c [3]float64, s float64) [3]float64 {
+	maxI, minI := 0, 0
+	for i := 1; i < 3; i++ {
+		if c[i] > c[maxI] {
+			maxI = i
+		}
+		if c[i] < c[minI] {
+			minI = i
+		}
+	}
+	if maxI == minI {
+		return [3]float64{}
+	}
+	midI := 3 - maxI - minI
+
+	var result [3]float64
+	result[midI] = (c[midI] - c[minI]) * s / (c[maxI] - c[minI])
+	result[maxI] = s
+
+	return result
+}
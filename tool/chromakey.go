@@ -0,0 +1,74 @@
+package tool
+
+import (
+	"image/color"
+	"math"
+)
+
+// 抠像参数，距离在 Lab 空间中计算
+type ChromaKeyOptions struct {
+	Key       color.RGBA // 背景色
+	Tolerance float64    // 与背景色的距离不超过该值时完全透明
+	Softness  float64    // 距离在 (Tolerance, Tolerance+Softness) 之间时半透明过渡
+	Spill     float64    // 溢色抑制强度 [0, 1]，去掉前景边缘反射的背景色，只作用于距离小于 Tolerance+2*Softness 的像素
+}
+
+func DefaultChromaKeyOptions() ChromaKeyOptions {
+	return ChromaKeyOptions{Key: color.RGBA{G: 255, A: 255}, Tolerance: 40, Softness: 30, Spill: 0.8}
+}
+
+// 把接近背景色的像素变为透明
+func (ip *ImgProcessor)ChromaKey(il *ImgLoader, opt ChromaKeyOptions) *ImgLoader {
+	kl, ka, kb := SRGBToLab(float64(opt.Key.R)/255, float64(opt.Key.G)/255, float64(opt.Key.B)/255)
+	// 背景色在 a-b 平面上的单位方向，溢色抑制时去掉像素在该方向上的分量
+	chroma := math.Hypot(ka, kb)
+	var ua, ub float64
+	if chroma > 0 {
+		ua, ub = ka/chroma, kb/chroma
+	}
+
+	return mapRGBA(il, func(p []uint8) {
+		l, a, b := SRGBToLab(float64(p[0])/255, float64(p[1])/255, float64(p[2])/255)
+
+		// 亮度差只按 1/4 计入，以容忍背景布光不均
+		d := math.Sqrt((l-kl)*(l-kl)/16 + (a-ka)*(a-ka) + (b-kb)*(b-kb))
+		keep := 1.0
+		if d <= opt.Tolerance {
+			keep = 0
+		} else if opt.Softness > 0 && d < opt.Tolerance+opt.Softness {
+			keep = (d - opt.Tolerance) / opt.Softness
+		}
+		p[3] = clampUint8(float64(p[3]) * keep)
+
+		// 过渡带之外再往外的 Softness 内逐渐减弱，远离背景色的前景颜色保持不变
+		spill := opt.Spill
+		if far := d - opt.Tolerance - opt.Softness; far > 0 {
+			spill = 0
+			if opt.Softness > 0 {
+				spill = opt.Spill * math.Max(0, 1-far/opt.Softness)
+			}
+		}
+
+		if spill > 0 && p[3] > 0 {
+			if c := a*ua + b*ub; c > 0 {
+				a -= spill * c * ua
+				b -= spill * c * ub
+				r, g, bb := LabToSRGB(l, a, b)
+				p[0], p[1], p[2] = clampUint8(r*255), clampUint8(g*255), clampUint8(bb*255)
+			}
+		}
+	})
+}
+
+// 抠像后合成到新背景上，背景按填充方式缩放裁剪到与前景相同的大小
+func (ip *ImgProcessor)ChromaKeyOver(il, bg *ImgLoader, opt ChromaKeyOptions) *ImgLoader {
+	bg = ip.Thumbnail(bg, il.GetMX(), il.GetMY(), ThumbFill, color.RGBA{})
+	result := ip.Blend(bg, ip.ChromaKey(il, opt), BlendNormal, 1)
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: result.GetMatrix(),
+		img: il.GetImg(),
+	}
+}
@@ -0,0 +1,77 @@
+package tool
+
+import (
+	"image/color"
+	"testing"
+)
+
+func solidImage(width, height int, c color.RGBA) *ImgLoader {
+	matrix := NewRGBAMatrix(height, width)
+	for hi := range matrix {
+		for wi := range matrix[hi] {
+			matrix[hi][wi][0], matrix[hi][wi][1], matrix[hi][wi][2], matrix[hi][wi][3] = c.R, c.G, c.B, c.A
+		}
+	}
+
+	return &ImgLoader{filename: "solid", matrix: matrix}
+}
+
+func TestChromaKey(t *testing.T) {
+	ip := &ImgProcessor{}
+	opt := DefaultChromaKeyOptions()
+
+	p := ip.ChromaKey(solidImage(2, 2, opt.Key), opt).GetMatrix()[0][0]
+	if p[3] != 0 {
+		t.Errorf("key color: alpha = %d, want 0", p[3])
+	}
+
+	// 与绿色相距较远、在 a-b 平面上偏向绿色方向的颜色（橙色、肤色），不应被当作溢色处理
+	for _, c := range []color.RGBA{
+		{R: 230, G: 150, B: 40, A: 255},
+		{R: 224, G: 172, B: 140, A: 255},
+		{R: 200, G: 30, B: 60, A: 255},
+	} {
+		p := ip.ChromaKey(solidImage(2, 2, c), opt).GetMatrix()[0][0]
+		if p[0] != c.R || p[1] != c.G || p[2] != c.B || p[3] != c.A {
+			t.Errorf("distant color %v changed to %v", c, p)
+		}
+	}
+
+	// 过渡带中的偏绿像素半透明，并去掉一部分绿色
+	c := color.RGBA{R: 100, G: 200, B: 100, A: 255}
+	p = ip.ChromaKey(solidImage(2, 2, c), opt).GetMatrix()[0][0]
+	if p[3] == 0 || p[3] == 255 {
+		t.Errorf("edge color: alpha = %d, want partially transparent", p[3])
+	}
+	if int(p[1])-int(p[0]) >= int(c.G)-int(c.R) {
+		t.Errorf("edge color %v: green spill not reduced in %v", c, p)
+	}
+
+	opt.Spill = 0
+	p = ip.ChromaKey(solidImage(2, 2, c), opt).GetMatrix()[0][0]
+	if p[0] != c.R || p[1] != c.G || p[2] != c.B {
+		t.Errorf("spill disabled: color changed to %v", p)
+	}
+}
+
+func TestChromaKeyOver(t *testing.T) {
+	ip := &ImgProcessor{}
+	opt := DefaultChromaKeyOptions()
+
+	fg := solidImage(30, 20, opt.Key)
+	fg.matrix[5][5][0], fg.matrix[5][5][1], fg.matrix[5][5][2] = 200, 30, 60
+	bg := solidImage(50, 60, color.RGBA{B: 255, A: 255})
+
+	result := ip.ChromaKeyOver(fg, bg, opt)
+	if result.GetMX() != 30 || result.GetMY() != 20 {
+		t.Fatalf("result is %dx%d, want 30x20", result.GetMX(), result.GetMY())
+	}
+
+	matrix := result.GetMatrix()
+	if p := matrix[0][0]; p[0] != 0 || p[1] != 0 || p[2] != 255 || p[3] != 255 {
+		t.Errorf("keyed pixel = %v, want the blue background", p)
+	}
+	if p := matrix[5][5]; p[0] != 200 || p[1] != 30 || p[2] != 60 {
+		t.Errorf("foreground pixel = %v, want unchanged", p)
+	}
+}
@@ -0,0 +1,122 @@
+package tool
+
+import "math"
+
+// 色相、饱和度等颜色调整，均在 HSL 空间中完成
+
+// 以 Center 为中心、宽 Width 度的色相范围，两侧各有 Feather 度的渐变过渡
+type HueRange struct {
+	Center  float64
+	Width   float64
+	Feather float64
+}
+
+// 选区内像素的调整量：Hue 为色相偏移（度），Saturation 与 Lightness 范围 [-1, 1]
+type SelectiveAdjust struct {
+	Hue        float64
+	Saturation float64
+	Lightness  float64
+}
+
+// 肤色所在的色相中心与宽度，鲜艳度调整时会避开这一范围
+const (
+	skinHue      = 25
+	skinHueWidth = 25
+)
+
+// 旋转色相，degrees 可正可负
+func (ip *ImgProcessor)HueRotate(il *ImgLoader, degrees float64) *ImgLoader {
+	return adjustHSL(il, func(h, s, l float64) (float64, float64, float64) {
+		return h + degrees, s, l
+	})
+}
+
+// 调整饱和度，amount 范围 [-1, 1]，-1 为完全去色，1 为饱和度加倍
+func (ip *ImgProcessor)AdjustSaturation(il *ImgLoader, amount float64) *ImgLoader {
+	amount = math.Max(-1, math.Min(1, amount))
+
+	return adjustHSL(il, func(h, s, l float64) (float64, float64, float64) {
+		return h, s * (1 + amount), l
+	})
+}
+
+// 调整鲜艳度，amount 范围 [-1, 1]。
+// 与饱和度不同，越是不饱和的像素变化越大，已经很鲜艳的像素与肤色几乎不受影响
+func (ip *ImgProcessor)AdjustVibrance(il *ImgLoader, amount float64) *ImgLoader {
+	amount = math.Max(-1, math.Min(1, amount))
+
+	return adjustHSL(il, func(h, s, l float64) (float64, float64, float64) {
+		d := hueDistance(h, skinHue) / skinHueWidth
+		protect := 1 - 0.7*math.Exp(-d*d)
+		return h, s * (1 + amount*(1-s)*protect), l
+	})
+}
+
+// 只调整色相落在 r 内的像素，灰色像素按其饱和度减弱影响
+func (ip *ImgProcessor)SelectiveColor(il *ImgLoader, r HueRange, adj SelectiveAdjust) *ImgLoader {
+	adj.Saturation = math.Max(-1, math.Min(1, adj.Saturation))
+	adj.Lightness = math.Max(-1, math.Min(1, adj.Lightness))
+
+	return adjustHSL(il, func(h, s, l float64) (float64, float64, float64) {
+		w := r.weight(h) * math.Min(1, s*4)
+		if w == 0 {
+			return h, s, l
+		}
+
+		h += adj.Hue * w
+		s *= 1 + adj.Saturation*w
+		if adj.Lightness > 0 {
+			l += (1 - l) * adj.Lightness * w
+		} else {
+			l += l * adj.Lightness * w
+		}
+		return h, s, l
+	})
+}
+
+// 色相 h 属于该范围的程度，[0, 1]
+func (r HueRange)weight(h float64) float64 {
+	d := hueDistance(h, r.Center) - r.Width/2
+	switch {
+	case d <= 0:
+		return 1
+	case d >= r.Feather:
+		return 0
+	}
+
+	// smoothstep 过渡
+	t := 1 - d/r.Feather
+	return t * t * (3 - 2*t)
+}
+
+// 两个色相之间的最短角距离，[0, 180]
+func hueDistance(a, b float64) float64 {
+	d := math.Mod(math.Abs(a-b), 360)
+	if d > 180 {
+		d = 360 - d
+	}
+
+	return d
+}
+
+// 把图片转换到 HSL，逐像素执行 fn 后转回 sRGB，S 与 L 会被限制在 [0, 1]
+func adjustHSL(il *ImgLoader, fn func(h, s, l float64) (float64, float64, float64)) *ImgLoader {
+	fi := NewFloatImage(il).Convert(SpaceHSL)
+
+	for i := 0; i < len(fi.Pix); i += 4 {
+		h, s, l := fn(fi.Pix[i], fi.Pix[i+1], fi.Pix[i+2])
+		if h = math.Mod(h, 360); h < 0 {
+			h += 360
+		}
+		fi.Pix[i] = h
+		fi.Pix[i+1] = math.Max(0, math.Min(1, s))
+		fi.Pix[i+2] = math.Max(0, math.Min(1, l))
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: fi.Matrix(),
+		img: il.GetImg(),
+	}
+}
@@ -0,0 +1,401 @@
+package tool
+
+import "math"
+
+// 色彩空间，各分量的取值范围：
+//   SpaceSRGB / SpaceLinearRGB: R、G、B 为 [0, 1]
+//   SpaceHSV / SpaceHSL: H 为 [0, 360)，S、V / L 为 [0, 1]
+//   SpaceXYZ: D65 白点，Y 为 [0, 1]
+//   SpaceLab: L 为 [0, 100]，a、b 约为 [-128, 127]
+//   SpaceLCh: L 为 [0, 100]，C 为 [0, ~150]，h 为 [0, 360)
+//   SpaceYCbCr: BT.601 全范围，Y 为 [0, 1]，Cb、Cr 为 [-0.5, 0.5]
+type ColorSpace int
+
+const (
+	SpaceSRGB ColorSpace = iota
+	SpaceLinearRGB
+	SpaceHSV
+	SpaceHSL
+	SpaceXYZ
+	SpaceLab
+	SpaceLCh
+	SpaceYCbCr
+)
+
+// D65 参考白
+const (
+	whiteX = 0.95047
+	whiteY = 1.0
+	whiteZ = 1.08883
+)
+
+// 浮点图像，每个像素依次存放三个颜色分量与透明度（[0, 1]），分量含义由 Space 决定
+type FloatImage struct {
+	Width  int
+	Height int
+	Space  ColorSpace
+	Pix    []float64
+}
+
+// 由 ImgLoader 构建 sRGB 空间的浮点图像
+func NewFloatImage(il *ImgLoader) *FloatImage {
+	matrix := il.GetMatrix()
+	fi := &FloatImage{Width: il.GetMX(), Height: il.GetMY(), Space: SpaceSRGB}
+	fi.Pix = make([]float64, fi.Width*fi.Height*4)
+
+	for hi := range matrix {
+		for wi := range matrix[hi] {
+			i := (hi*fi.Width + wi) * 4
+			for c := 0; c < 4; c++ {
+				fi.Pix[i+c] = float64(matrix[hi][wi][c]) / 255
+			}
+		}
+	}
+
+	return fi
+}
+
+func (fi *FloatImage)At(x, y int) (c0, c1, c2, alpha float64) {
+	i := (y*fi.Width + x) * 4
+	return fi.Pix[i], fi.Pix[i+1], fi.Pix[i+2], fi.Pix[i+3]
+}
+
+func (fi *FloatImage)Set(x, y int, c0, c1, c2, alpha float64) {
+	i := (y*fi.Width + x) * 4
+	fi.Pix[i], fi.Pix[i+1], fi.Pix[i+2], fi.Pix[i+3] = c0, c1, c2, alpha
+}
+
+// 转换到另一个色彩空间，返回新的图像，原图不变
+func (fi *FloatImage)Convert(space ColorSpace) *FloatImage {
+	dst := &FloatImage{Width: fi.Width, Height: fi.Height, Space: space, Pix: make([]float64, len(fi.Pix))}
+
+	for i := 0; i < len(fi.Pix); i += 4 {
+		c0, c1, c2 := fi.Pix[i], fi.Pix[i+1], fi.Pix[i+2]
+		if space != fi.Space {
+			r, g, b := toSRGB(fi.Space, c0, c1, c2)
+			c0, c1, c2 = fromSRGB(space, r, g, b)
+		}
+		dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = c0, c1, c2, fi.Pix[i+3]
+	}
+
+	return dst
+}
+
+// 转回 sRGB 并量化为 RGBA 矩阵
+func (fi *FloatImage)Matrix() [][][]uint8 {
+	matrix := NewRGBAMatrix(fi.Height, fi.Width)
+
+	for hi := range matrix {
+		for wi := range matrix[hi] {
+			c0, c1, c2, alpha := fi.At(wi, hi)
+			r, g, b := toSRGB(fi.Space, c0, c1, c2)
+			matrix[hi][wi][0] = clampUint8(r * 255)
+			matrix[hi][wi][1] = clampUint8(g * 255)
+			matrix[hi][wi][2] = clampUint8(b * 255)
+			matrix[hi][wi][3] = clampUint8(alpha * 255)
+		}
+	}
+
+	return matrix
+}
+
+func toSRGB(space ColorSpace, c0, c1, c2 float64) (r, g, b float64) {
+	switch space {
+	case SpaceLinearRGB:
+		return LinearToSRGB(c0), LinearToSRGB(c1), LinearToSRGB(c2)
+	case SpaceHSV:
+		return HSVToRGB(c0, c1, c2)
+	case SpaceHSL:
+		return HSLToRGB(c0, c1, c2)
+	case SpaceXYZ:
+		return XYZToSRGB(c0, c1, c2)
+	case SpaceLab:
+		return LabToSRGB(c0, c1, c2)
+	case SpaceLCh:
+		return LabToSRGB(LChToLab(c0, c1, c2))
+	case SpaceYCbCr:
+		return YCbCrToRGB(c0, c1, c2)
+	}
+
+	return c0, c1, c2
+}
+
+func fromSRGB(space ColorSpace, r, g, b float64) (c0, c1, c2 float64) {
+	switch space {
+	case SpaceLinearRGB:
+		return SRGBToLinear(r), SRGBToLinear(g), SRGBToLinear(b)
+	case SpaceHSV:
+		return RGBToHSV(r, g, b)
+	case SpaceHSL:
+		return RGBToHSL(r, g, b)
+	case SpaceXYZ:
+		return SRGBToXYZ(r, g, b)
+	case SpaceLab:
+		return SRGBToLab(r, g, b)
+	case SpaceLCh:
+		return LabToLCh(SRGBToLab(r, g, b))
+	case SpaceYCbCr:
+		return RGBToYCbCr(r, g, b)
+	}
+
+	return r, g, b
+}
+
+// sRGB 传递函数，输入输出范围均为 [0, 1]
+func SRGBToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func LinearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+func RGBToHSV(r, g, b float64) (h, s, v float64) {
+	max := math.Max(r, math.Max(g, b))
+	min := math.Min(r, math.Min(g, b))
+	v = max
+	if max > 0 {
+		s = (max - min) / max
+	}
+	h = hue(r, g, b, max, min)
+
+	return
+}
+
+func HSVToRGB(h, s, v float64) (r, g, b float64) {
+	c := v * s
+	return hueToRGB(h, c, v-c)
+}
+
+func RGBToHSL(r, g, b float64) (h, s, l float64) {
+	max := math.Max(r, math.Max(g, b))
+	min := math.Min(r, math.Min(g, b))
+	l = (max + min) / 2
+	if d := max - min; d > 0 {
+		s = d / (1 - math.Abs(2*l-1))
+	}
+	h = hue(r, g, b, max, min)
+
+	return
+}
+
+func HSLToRGB(h, s, l float64) (r, g, b float64) {
+	c := (1 - math.Abs(2*l-1)) * s
+	return hueToRGB(h, c, l-c/2)
+}
+
+// HSV 与 HSL 共用的色相计算，单位为度
+func hue(r, g, b, max, min float64) float64 {
+	d := max - min
+	if d == 0 {
+		return 0
+	}
+
+	var h float64
+	switch max {
+	case r:
+		h = math.Mod((g-b)/d, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+
+	return h
+}
+
+// 由色相、色度 c 与最小分量 m 还原 RGB
+func hueToRGB(h, c, m float64) (r, g, b float64) {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	hp := h / 60
+	x := c * (1 - math.Abs(math.Mod(hp, 2)-1))
+
+	switch int(hp) {
+	case 0:
+		r, g, b = c, x, 0
+	case 1:
+		r, g, b = x, c, 0
+	case 2:
+		r, g, b = 0, c, x
+	case 3:
+		r, g, b = 0, x, c
+	case 4:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+
+	return r + m, g + m, b + m
+}
+
+func LinearRGBToXYZ(r, g, b float64) (x, y, z float64) {
+	x = 0.4124564*r + 0.3575761*g + 0.1804375*b
+	y = 0.2126729*r + 0.7151522*g + 0.0721750*b
+	z = 0.0193339*r + 0.1191920*g + 0.9503041*b
+	return
+}
+
+func XYZToLinearRGB(x, y, z float64) (r, g, b float64) {
+	r = 3.2404542*x - 1.5371385*y - 0.4985314*z
+	g = -0.9692660*x + 1.8760108*y + 0.0415560*z
+	b = 0.0556434*x - 0.2040259*y + 1.0572252*z
+	return
+}
+
+func SRGBToXYZ(r, g, b float64) (x, y, z float64) {
+	return LinearRGBToXYZ(SRGBToLinear(r), SRGBToLinear(g), SRGBToLinear(b))
+}
+
+func XYZToSRGB(x, y, z float64) (r, g, b float64) {
+	r, g, b = XYZToLinearRGB(x, y, z)
+	return LinearToSRGB(r), LinearToSRGB(g), LinearToSRGB(b)
+}
+
+func XYZToLab(x, y, z float64) (l, a, b float64) {
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+
+	fx, fy, fz := f(x/whiteX), f(y/whiteY), f(z/whiteZ)
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
+
+func LabToXYZ(l, a, b float64) (x, y, z float64) {
+	fy := (l + 16) / 116
+	fx := fy + a/500
+	fz := fy - b/200
+
+	inv := func(t float64) float64 {
+		if t3 := t * t * t; t3 > 216.0/24389 {
+			return t3
+		}
+		return (116*t - 16) * 27 / 24389
+	}
+
+	return inv(fx) * whiteX, inv(fy) * whiteY, inv(fz) * whiteZ
+}
+
+func SRGBToLab(r, g, b float64) (l, a, bb float64) {
+	return XYZToLab(SRGBToXYZ(r, g, b))
+}
+
+func LabToSRGB(l, a, b float64) (r, g, bb float64) {
+	return XYZToSRGB(LabToXYZ(l, a, b))
+}
+
+func LabToLCh(l, a, b float64) (ll, c, h float64) {
+	h = math.Atan2(b, a) * 180 / math.Pi
+	if h < 0 {
+		h += 360
+	}
+
+	return l, math.Hypot(a, b), h
+}
+
+func LChToLab(l, c, h float64) (ll, a, b float64) {
+	rad := h * math.Pi / 180
+	return l, c * math.Cos(rad), c * math.Sin(rad)
+}
+
+// BT.601 全范围 YCbCr，与 JPEG 使用的定义一致
+func RGBToYCbCr(r, g, b float64) (y, cb, cr float64) {
+	y = 0.299*r + 0.587*g + 0.114*b
+	cb = -0.168736*r - 0.331264*g + 0.5*b
+	cr = 0.5*r - 0.418688*g - 0.081312*b
+	return
+}
+
+func YCbCrToRGB(y, cb, cr float64) (r, g, b float64) {
+	r = y + 1.402*cr
+	g = y - 0.344136*cb - 0.714136*cr
+	b = y + 1.772*cb
+	return
+}
+
+// CIE76 色差，即 Lab 空间中的欧氏距离
+func DeltaE76(l1, a1, b1, l2, a2, b2 float64) float64 {
+	return math.Sqrt((l1-l2)*(l1-l2) + (a1-a2)*(a1-a2) + (b1-b2)*(b1-b2))
+}
+
+// CIEDE2000 色差，比 CIE76 更符合人眼感知
+func DeltaE2000(l1, a1, b1, l2, a2, b2 float64) float64 {
+	const rad = math.Pi / 180
+
+	c1, c2 := math.Hypot(a1, b1), math.Hypot(a2, b2)
+	cBar := (c1 + c2) / 2
+	cBar7 := math.Pow(cBar, 7)
+	g := 0.5 * (1 - math.Sqrt(cBar7/(cBar7+math.Pow(25, 7))))
+
+	a1p, a2p := a1*(1+g), a2*(1+g)
+	c1p, c2p := math.Hypot(a1p, b1), math.Hypot(a2p, b2)
+
+	hp := func(a, b float64) float64 {
+		if a == 0 && b == 0 {
+			return 0
+		}
+		h := math.Atan2(b, a) / rad
+		if h < 0 {
+			h += 360
+		}
+		return h
+	}
+	h1p, h2p := hp(a1p, b1), hp(a2p, b2)
+
+	dL := l2 - l1
+	dC := c2p - c1p
+	var dh float64
+	if c1p*c2p != 0 {
+		dh = h2p - h1p
+		if dh > 180 {
+			dh -= 360
+		} else if dh < -180 {
+			dh += 360
+		}
+	}
+	dH := 2 * math.Sqrt(c1p*c2p) * math.Sin(dh/2*rad)
+
+	lBar := (l1 + l2) / 2
+	cBarP := (c1p + c2p) / 2
+	hBarP := h1p + h2p
+	if c1p*c2p != 0 {
+		// 两色相恰好相差 180 度时按不超过 180 度处理，留出浮点误差的余量
+		if math.Abs(h1p-h2p) > 180+1e-9 {
+			if hBarP < 360 {
+				hBarP += 360
+			} else {
+				hBarP -= 360
+			}
+		}
+		hBarP /= 2
+	}
+
+	t := 1 - 0.17*math.Cos((hBarP-30)*rad) + 0.24*math.Cos(2*hBarP*rad) +
+		0.32*math.Cos((3*hBarP+6)*rad) - 0.20*math.Cos((4*hBarP-63)*rad)
+	dTheta := 30 * math.Exp(-((hBarP-275)/25)*((hBarP-275)/25))
+	cBarP7 := math.Pow(cBarP, 7)
+	rc := 2 * math.Sqrt(cBarP7/(cBarP7+math.Pow(25, 7)))
+	sl := 1 + 0.015*(lBar-50)*(lBar-50)/math.Sqrt(20+(lBar-50)*(lBar-50))
+	sc := 1 + 0.045*cBarP
+	sh := 1 + 0.015*cBarP*t
+	rt := -math.Sin(2*dTheta*rad) * rc
+
+	return math.Sqrt((dL/sl)*(dL/sl) + (dC/sc)*(dC/sc) + (dH/sh)*(dH/sh) + rt*(dC/sc)*(dH/sh))
+}
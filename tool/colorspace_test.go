@@ -0,0 +1,127 @@
+package tool
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// 转换矩阵只有 7 位有效数字，正反变换并非严格互逆
+func TestSRGBXYZLabRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		r, g, b := rnd.Float64(), rnd.Float64(), rnd.Float64()
+
+		x, y, z := SRGBToXYZ(r, g, b)
+		if rr, gg, bb := XYZToSRGB(x, y, z); math.Abs(rr-r) > 1e-5 || math.Abs(gg-g) > 1e-5 || math.Abs(bb-b) > 1e-5 {
+			t.Fatalf("sRGB -> XYZ -> sRGB: (%v, %v, %v) -> (%v, %v, %v)", r, g, b, rr, gg, bb)
+		}
+
+		l, a, bl := XYZToLab(x, y, z)
+		if xx, yy, zz := LabToXYZ(l, a, bl); math.Abs(xx-x) > 1e-9 || math.Abs(yy-y) > 1e-9 || math.Abs(zz-z) > 1e-9 {
+			t.Fatalf("XYZ -> Lab -> XYZ: (%v, %v, %v) -> (%v, %v, %v)", x, y, z, xx, yy, zz)
+		}
+
+		if rr, gg, bb := LabToSRGB(SRGBToLab(r, g, b)); math.Abs(rr-r) > 1e-5 || math.Abs(gg-g) > 1e-5 || math.Abs(bb-b) > 1e-5 {
+			t.Fatalf("sRGB -> Lab -> sRGB: (%v, %v, %v) -> (%v, %v, %v)", r, g, b, rr, gg, bb)
+		}
+
+		if ll, aa, bb := LChToLab(LabToLCh(l, a, bl)); math.Abs(ll-l) > 1e-9 || math.Abs(aa-a) > 1e-9 || math.Abs(bb-bl) > 1e-9 {
+			t.Fatalf("Lab -> LCh -> Lab: (%v, %v, %v) -> (%v, %v, %v)", l, a, bl, ll, aa, bb)
+		}
+	}
+}
+
+func TestSRGBToLabReference(t *testing.T) {
+	cases := []struct {
+		r, g, b  float64
+		l, a, bl float64
+	}{
+		{1, 1, 1, 100, 0, 0},
+		{0, 0, 0, 0, 0, 0},
+		{1, 0, 0, 53.2408, 80.0925, 67.2032},
+		{0, 1, 0, 87.7347, -86.1827, 83.1793},
+		{0, 0, 1, 32.2970, 79.1875, -107.8602},
+	}
+
+	for _, c := range cases {
+		l, a, bl := SRGBToLab(c.r, c.g, c.b)
+		if math.Abs(l-c.l) > 1e-3 || math.Abs(a-c.a) > 1e-3 || math.Abs(bl-c.bl) > 1e-3 {
+			t.Errorf("SRGBToLab(%v, %v, %v) = (%.4f, %.4f, %.4f), want (%v, %v, %v)", c.r, c.g, c.b, l, a, bl, c.l, c.a, c.bl)
+		}
+	}
+}
+
+// Sharma, Wu, Dalal, "The CIEDE2000 Color-Difference Formula: Implementation Notes,
+// Supplementary Test Data, and Mathematical Observations", 2005, 表 1
+func TestDeltaE2000Sharma(t *testing.T) {
+	cases := [][7]float64{
+		{50.0000, 2.6772, -79.7751, 50.0000, 0.0000, -82.7485, 2.0425},
+		{50.0000, 3.1571, -77.2803, 50.0000, 0.0000, -82.7485, 2.8615},
+		{50.0000, 2.8361, -74.0200, 50.0000, 0.0000, -82.7485, 3.4412},
+		{50.0000, -1.3802, -84.2814, 50.0000, 0.0000, -82.7485, 1.0000},
+		{50.0000, -1.1848, -84.8006, 50.0000, 0.0000, -82.7485, 1.0000},
+		{50.0000, -0.9009, -85.5211, 50.0000, 0.0000, -82.7485, 1.0000},
+		{50.0000, 0.0000, 0.0000, 50.0000, -1.0000, 2.0000, 2.3669},
+		{50.0000, -1.0000, 2.0000, 50.0000, 0.0000, 0.0000, 2.3669},
+		{50.0000, 2.4900, -0.0010, 50.0000, -2.4900, 0.0009, 7.1792},
+		{50.0000, 2.4900, -0.0010, 50.0000, -2.4900, 0.0010, 7.1792},
+		{50.0000, 2.4900, -0.0010, 50.0000, -2.4900, 0.0011, 7.2195},
+		{50.0000, 2.4900, -0.0010, 50.0000, -2.4900, 0.0012, 7.2195},
+		{50.0000, -0.0010, 2.4900, 50.0000, 0.0009, -2.4900, 4.8045},
+		{50.0000, -0.0010, 2.4900, 50.0000, 0.0010, -2.4900, 4.8045},
+		{50.0000, -0.0010, 2.4900, 50.0000, 0.0011, -2.4900, 4.7461},
+		{50.0000, 2.5000, 0.0000, 50.0000, 0.0000, -2.5000, 4.3065},
+		{50.0000, 2.5000, 0.0000, 73.0000, 25.0000, -18.0000, 27.1492},
+		{50.0000, 2.5000, 0.0000, 61.0000, -5.0000, 29.0000, 22.8977},
+		{50.0000, 2.5000, 0.0000, 56.0000, -27.0000, -3.0000, 31.9030},
+		{50.0000, 2.5000, 0.0000, 58.0000, 24.0000, 15.0000, 19.4535},
+		{50.0000, 2.5000, 0.0000, 50.0000, 3.1736, 0.5854, 1.0000},
+		{50.0000, 2.5000, 0.0000, 50.0000, 3.2972, 0.0000, 1.0000},
+		{50.0000, 2.5000, 0.0000, 50.0000, 1.8634, 0.5757, 1.0000},
+		{50.0000, 2.5000, 0.0000, 50.0000, 3.2592, 0.3350, 1.0000},
+		{60.2574, -34.0099, 36.2677, 60.4626, -34.1751, 39.4387, 1.2644},
+		{63.0109, -31.0961, -5.8663, 62.8187, -29.7946, -4.0864, 1.2630},
+		{61.2901, 3.7196, -5.3901, 61.4292, 2.2480, -4.9620, 1.8731},
+		{35.0831, -44.1164, 3.7933, 35.0232, -40.0716, 1.5901, 1.8645},
+		{22.7233, 20.0904, -46.6940, 23.0331, 14.9730, -42.5619, 2.0373},
+		{36.4612, 47.8580, 18.3852, 36.2715, 50.5065, 21.2231, 1.4146},
+		{90.8027, -2.0831, 1.4410, 91.1528, -1.6435, 0.0447, 1.4441},
+		{90.9257, -0.5406, -0.9208, 88.6381, -0.8985, -0.7239, 1.5381},
+		{6.7747, -0.2908, -2.4247, 5.8714, -0.0985, -2.2286, 0.6377},
+		{2.0776, 0.0795, -1.1350, 0.9033, -0.0636, -0.5514, 0.9082},
+	}
+
+	for i, c := range cases {
+		if d := DeltaE2000(c[0], c[1], c[2], c[3], c[4], c[5]); math.Abs(d-c[6]) > 1e-4 {
+			t.Errorf("pair %d: DeltaE2000 = %.4f, want %.4f", i+1, d, c[6])
+		}
+		// 色差应对称
+		if d := DeltaE2000(c[3], c[4], c[5], c[0], c[1], c[2]); math.Abs(d-c[6]) > 1e-4 {
+			t.Errorf("pair %d reversed: DeltaE2000 = %.4f, want %.4f", i+1, d, c[6])
+		}
+	}
+}
+
+func TestFloatImageConvertRoundTrip(t *testing.T) {
+	matrix := NewRGBAMatrix(8, 8)
+	for hi := range matrix {
+		for wi := range matrix[hi] {
+			matrix[hi][wi][0], matrix[hi][wi][1], matrix[hi][wi][2], matrix[hi][wi][3] = uint8(hi*32), uint8(wi*32), uint8(255-hi*wi), 255
+		}
+	}
+	fi := NewFloatImage(&ImgLoader{matrix: matrix})
+
+	for _, space := range []ColorSpace{SpaceLinearRGB, SpaceHSV, SpaceHSL, SpaceXYZ, SpaceLab, SpaceLCh, SpaceYCbCr} {
+		result := fi.Convert(space).Convert(SpaceSRGB).Matrix()
+		for hi := range matrix {
+			for wi := range matrix[hi] {
+				for c := 0; c < 4; c++ {
+					if result[hi][wi][c] != matrix[hi][wi][c] {
+						t.Fatalf("space %d: pixel (%d, %d) = %v, want %v", space, wi, hi, result[hi][wi], matrix[hi][wi])
+					}
+				}
+			}
+		}
+	}
+}
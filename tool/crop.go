@@ -0,0 +1,277 @@
+package tool
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// 对齐位置，用于画布扩展、水印摆放等场景
+type Anchor int
+
+const (
+	AnchorTopLeft Anchor = iota
+	AnchorTop
+	AnchorTopRight
+	AnchorLeft
+	AnchorCenter
+	AnchorRight
+	AnchorBottomLeft
+	AnchorBottom
+	AnchorBottomRight
+)
+
+var anchorNames = []string{"topleft", "top", "topright", "left", "center", "right", "bottomleft", "bottom", "bottomright"}
+
+func ParseAnchor(name string) (Anchor, error) {
+	for i, v := range anchorNames {
+		if strings.ToLower(name) == v {
+			return Anchor(i), nil
+		}
+	}
+
+	return AnchorCenter, fmt.Errorf("unknown anchor: %s", name)
+}
+
+func AnchorNames() []string {
+	return anchorNames
+}
+
+// 把 inner 大小的区域按锚点放进 outer 大小的区域，返回左上角坐标，可能为负
+func (a Anchor)offset(outerWidth, outerHeight, innerWidth, innerHeight int) (x, y int) {
+	switch a % 3 {
+	case 1:
+		x = (outerWidth - innerWidth) / 2
+	case 2:
+		x = outerWidth - innerWidth
+	}
+	switch a / 3 {
+	case 1:
+		y = (outerHeight - innerHeight) / 2
+	case 2:
+		y = outerHeight - innerHeight
+	}
+
+	return
+}
+
+type PadMode int
+
+const (
+	PadColor     PadMode = iota // 以指定颜色填充
+	PadReplicate                // 复制边缘像素
+	PadReflect                  // 以边缘为轴镜像
+)
+
+// 裁剪出左上角为 (x, y)、大小为 w x h 的区域，超出图片的部分会被裁掉
+func (ip *ImgProcessor)Crop(il *ImgLoader, x, y, w, h int) (*ImgLoader, error) {
+	rect := image.Rect(x, y, x+w, y+h).Intersect(image.Rect(0, 0, il.GetMX(), il.GetMY()))
+	if rect.Empty() {
+		return nil, errors.New("crop region is outside of the image")
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: cropMatrix(il.GetMatrix(), rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy()),
+		img: il.GetImg(),
+	}, nil
+}
+
+// 按宽高比 ratioWidth:ratioHeight 从中心裁出尽可能大的区域
+func (ip *ImgProcessor)CropToAspect(il *ImgLoader, ratioWidth, ratioHeight float64) (*ImgLoader, error) {
+	if ratioWidth <= 0 || ratioHeight <= 0 {
+		return nil, errors.New("aspect ratio has to be greater than 0")
+	}
+
+	width, height := float64(il.GetMX()), float64(il.GetMY())
+	w := int(math.Max(1, math.Round(math.Min(width, height*ratioWidth/ratioHeight))))
+	h := int(math.Max(1, math.Round(math.Min(height, width*ratioHeight/ratioWidth))))
+
+	return ip.Crop(il, (il.GetMX()-w)/2, (il.GetMY()-h)/2, w, h)
+}
+
+// 去掉四周与左上角像素颜色相近的边框，tolerance 为各通道允许的最大差值
+func (ip *ImgProcessor)AutoTrim(il *ImgLoader, tolerance uint8) *ImgLoader {
+	src := il.GetMatrix()
+	border := src[0][0]
+
+	similar := func(p []uint8) bool {
+		for c := 0; c < 4; c++ {
+			d := int(p[c]) - int(border[c])
+			if d > int(tolerance) || -d > int(tolerance) {
+				return false
+			}
+		}
+		return true
+	}
+	rowUniform := func(hi int) bool {
+		for _, p := range src[hi] {
+			if !similar(p) {
+				return false
+			}
+		}
+		return true
+	}
+	colUniform := func(wi, top, bottom int) bool {
+		for hi := top; hi < bottom; hi++ {
+			if !similar(src[hi][wi]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	top, bottom := 0, il.GetMY()
+	for top < bottom && rowUniform(top) {
+		top++
+	}
+	for bottom > top && rowUniform(bottom-1) {
+		bottom--
+	}
+	if top == bottom {
+		// 整幅图都是边框色，原样返回
+		return &ImgLoader{
+			filename: il.GetFileName(),
+			format: il.GetFormat(),
+			matrix: src,
+			img: il.GetImg(),
+		}
+	}
+
+	left, right := 0, il.GetMX()
+	for left < right && colUniform(left, top, bottom) {
+		left++
+	}
+	for right > left && colUniform(right-1, top, bottom) {
+		right--
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: cropMatrix(src, left, top, right-left, bottom-top),
+		img: il.GetImg(),
+	}
+}
+
+// 在四周分别加上 top、right、bottom、left 像素的边，bg 只在 PadColor 模式下使用
+func (ip *ImgProcessor)Pad(il *ImgLoader, top, right, bottom, left int, mode PadMode, bg color.RGBA) *ImgLoader {
+	for _, v := range []*int{&top, &right, &bottom, &left} {
+		if *v < 0 {
+			*v = 0
+		}
+	}
+	src := il.GetMatrix()
+	height, width := il.GetMY(), il.GetMX()
+
+	imgMatrix := NewRGBAMatrix(height+top+bottom, width+left+right)
+	for hi := range imgMatrix {
+		for wi := range imgMatrix[hi] {
+			sy, sx := hi-top, wi-left
+			inside := sy >= 0 && sy < height && sx >= 0 && sx < width
+			switch {
+			case inside:
+			case mode == PadReplicate:
+				sy, sx = clampInt(sy, 0, height-1), clampInt(sx, 0, width-1)
+			case mode == PadReflect:
+				sy, sx = reflectIndex(sy, height), reflectIndex(sx, width)
+			default:
+				imgMatrix[hi][wi][0], imgMatrix[hi][wi][1], imgMatrix[hi][wi][2], imgMatrix[hi][wi][3] = bg.R, bg.G, bg.B, bg.A
+				continue
+			}
+			copy(imgMatrix[hi][wi], src[sy][sx])
+		}
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: imgMatrix,
+		img: il.GetImg(),
+	}
+}
+
+// 把画布改为 width x height，原图按 anchor 对齐，画布变小时裁掉超出部分，变大时用 bg 填充
+func (ip *ImgProcessor)ResizeCanvas(il *ImgLoader, width, height int, anchor Anchor, bg color.RGBA) *ImgLoader {
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	x, y := anchor.offset(width, height, il.GetMX(), il.GetMY())
+
+	canvas := NewRGBAMatrix(height, width)
+	for hi := range canvas {
+		for wi := range canvas[hi] {
+			canvas[hi][wi][0], canvas[hi][wi][1], canvas[hi][wi][2], canvas[hi][wi][3] = bg.R, bg.G, bg.B, bg.A
+		}
+	}
+	pasteMatrix(canvas, il.GetMatrix(), x, y)
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: canvas,
+		img: il.GetImg(),
+	}
+}
+
+// 只在 rect 区域内执行 op，再把结果贴回原位置。
+// op 改变了尺寸时结果仍以 rect 的左上角对齐，超出图片的部分被裁掉
+func (ip *ImgProcessor)ApplyToROI(il *ImgLoader, rect image.Rectangle, op func(*ImgLoader) *ImgLoader) (*ImgLoader, error) {
+	roi, err := ip.Crop(il, rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy())
+	if err != nil {
+		return nil, err
+	}
+	rect = rect.Intersect(image.Rect(0, 0, il.GetMX(), il.GetMY()))
+
+	imgMatrix := il.GetMatrix()
+	pasteMatrix(imgMatrix, op(roi).GetMatrix(), rect.Min.X, rect.Min.Y)
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: imgMatrix,
+		img: il.GetImg(),
+	}, nil
+}
+
+// 把 src 原样覆盖到 dst 的 (x, y) 处，超出 dst 的部分被忽略
+func pasteMatrix(dst, src [][][]uint8, x, y int) {
+	for hi := range src {
+		dy := y + hi
+		if dy < 0 || dy >= len(dst) {
+			continue
+		}
+		for wi := range src[hi] {
+			dx := x + wi
+			if dx < 0 || dx >= len(dst[dy]) {
+				continue
+			}
+			copy(dst[dy][dx], src[hi][wi])
+		}
+	}
+}
+
+// 以边缘为轴的镜像下标，例如 -1 -> 1，n -> n-2
+func reflectIndex(i, n int) int {
+	if n == 1 {
+		return 0
+	}
+
+	period := 2 * (n - 1)
+	i %= period
+	if i < 0 {
+		i += period
+	}
+	if i >= n {
+		i = period - i
+	}
+
+	return i
+}
@@ -0,0 +1,280 @@
+package tool
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 3D LUT 的插值方式
+type LUTInterpolation int
+
+const (
+	LUTTrilinear LUTInterpolation = iota
+	LUTTetrahedral
+)
+
+// Adobe / Resolve 的 .cube 查找表，支持 1D 与 3D 两种。
+// 3D 表按 R 变化最快、B 变化最慢的顺序存放，下标为 r + g*Size + b*Size*Size
+type CubeLUT struct {
+	Title     string
+	Dimension int
+	Size      int
+	DomainMin [3]float64
+	DomainMax [3]float64
+	Table     [][3]float64
+}
+
+func LoadCubeLUT(filename string) (*CubeLUT, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	lut := &CubeLUT{DomainMax: [3]float64{1, 1, 1}}
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "TITLE":
+			lut.Title = strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "TITLE")), "\"")
+		case "LUT_1D_SIZE", "LUT_3D_SIZE":
+			if lut.Dimension != 0 {
+				return nil, fmt.Errorf("line %d: mixed 1D and 3D tables are not supported", lineNo)
+			}
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("line %d: invalid %s", lineNo, fields[0])
+			}
+			lut.Size, err = strconv.Atoi(fields[1])
+			if err != nil || lut.Size < 2 {
+				return nil, fmt.Errorf("line %d: invalid %s", lineNo, fields[0])
+			}
+			lut.Dimension = 3
+			if fields[0] == "LUT_1D_SIZE" {
+				lut.Dimension = 1
+			}
+		case "DOMAIN_MIN", "DOMAIN_MAX":
+			v, err := parseTriple(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineNo, err.Error())
+			}
+			if fields[0] == "DOMAIN_MIN" {
+				lut.DomainMin = v
+			} else {
+				lut.DomainMax = v
+			}
+		default:
+			v, err := parseTriple(fields)
+			if err != nil {
+				// 忽略不认识的关键字，例如 LUT_3D_INPUT_RANGE 等厂商扩展
+				if fields[0][0] >= 'A' && fields[0][0] <= 'Z' {
+					continue
+				}
+				return nil, fmt.Errorf("line %d: %s", lineNo, err.Error())
+			}
+			lut.Table = append(lut.Table, v)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if lut.Dimension == 0 {
+		return nil, errors.New("missing LUT_1D_SIZE or LUT_3D_SIZE")
+	}
+	expected := lut.Size
+	if lut.Dimension == 3 {
+		expected = lut.Size * lut.Size * lut.Size
+	}
+	if len(lut.Table) != expected {
+		return nil, fmt.Errorf("expected %d entries, got %d", expected, len(lut.Table))
+	}
+
+	return lut, nil
+}
+
+func SaveCubeLUT(filename string, lut *CubeLUT) error {
+	var buf bytes.Buffer
+	if lut.Title != "" {
+		buf.WriteString("TITLE \"" + lut.Title + "\"\n")
+	}
+	if lut.Dimension == 1 {
+		buf.WriteString("LUT_1D_SIZE " + strconv.Itoa(lut.Size) + "\n")
+	} else {
+		buf.WriteString("LUT_3D_SIZE " + strconv.Itoa(lut.Size) + "\n")
+	}
+	buf.WriteString(fmt.Sprintf("DOMAIN_MIN %g %g %g\n", lut.DomainMin[0], lut.DomainMin[1], lut.DomainMin[2]))
+	buf.WriteString(fmt.Sprintf("DOMAIN_MAX %g %g %g\n", lut.DomainMax[0], lut.DomainMax[1], lut.DomainMax[2]))
+	for _, v := range lut.Table {
+		buf.WriteString(fmt.Sprintf("%.6f %.6f %.6f\n", v[0], v[1], v[2]))
+	}
+
+	return ioutil.WriteFile(filename, buf.Bytes(), 0666)
+}
+
+// 查找 (r, g, b) 对应的输出，输入输出范围与 DOMAIN 一致，通常为 [0, 1]
+func (lut *CubeLUT)Lookup(r, g, b float64, interp LUTInterpolation) (float64, float64, float64) {
+	in := [3]float64{r, g, b}
+	var idx [3]float64
+	for c := range in {
+		span := lut.DomainMax[c] - lut.DomainMin[c]
+		if span <= 0 {
+			span = 1
+		}
+		v := (in[c] - lut.DomainMin[c]) / span
+		idx[c] = math.Max(0, math.Min(1, v)) * float64(lut.Size-1)
+	}
+
+	if lut.Dimension == 1 {
+		var out [3]float64
+		for c := range idx {
+			i0 := int(idx[c])
+			i1 := clampInt(i0+1, 0, lut.Size-1)
+			f := idx[c] - float64(i0)
+			out[c] = lut.Table[i0][c]*(1-f) + lut.Table[i1][c]*f
+		}
+		return out[0], out[1], out[2]
+	}
+
+	r0, g0, b0 := int(idx[0]), int(idx[1]), int(idx[2])
+	r1, g1, b1 := clampInt(r0+1, 0, lut.Size-1), clampInt(g0+1, 0, lut.Size-1), clampInt(b0+1, 0, lut.Size-1)
+	fr, fg, fb := idx[0]-float64(r0), idx[1]-float64(g0), idx[2]-float64(b0)
+
+	at := func(r, g, b int) [3]float64 {
+		return lut.Table[r+g*lut.Size+b*lut.Size*lut.Size]
+	}
+	c000, c111 := at(r0, g0, b0), at(r1, g1, b1)
+
+	var out [3]float64
+	if interp == LUTTetrahedral {
+		// 按小数部分的大小关系把立方体分成 6 个四面体
+		for c := range out {
+			switch {
+			case fr >= fg && fg >= fb:
+				c100, c110 := at(r1, g0, b0), at(r1, g1, b0)
+				out[c] = (1-fr)*c000[c] + (fr-fg)*c100[c] + (fg-fb)*c110[c] + fb*c111[c]
+			case fr >= fb && fb >= fg:
+				c100, c101 := at(r1, g0, b0), at(r1, g0, b1)
+				out[c] = (1-fr)*c000[c] + (fr-fb)*c100[c] + (fb-fg)*c101[c] + fg*c111[c]
+			case fb >= fr && fr >= fg:
+				c001, c101 := at(r0, g0, b1), at(r1, g0, b1)
+				out[c] = (1-fb)*c000[c] + (fb-fr)*c001[c] + (fr-fg)*c101[c] + fg*c111[c]
+			case fg >= fr && fr >= fb:
+				c010, c110 := at(r0, g1, b0), at(r1, g1, b0)
+				out[c] = (1-fg)*c000[c] + (fg-fr)*c010[c] + (fr-fb)*c110[c] + fb*c111[c]
+			case fg >= fb && fb >= fr:
+				c010, c011 := at(r0, g1, b0), at(r0, g1, b1)
+				out[c] = (1-fg)*c000[c] + (fg-fb)*c010[c] + (fb-fr)*c011[c] + fr*c111[c]
+			default:
+				c001, c011 := at(r0, g0, b1), at(r0, g1, b1)
+				out[c] = (1-fb)*c000[c] + (fb-fg)*c001[c] + (fg-fr)*c011[c] + fr*c111[c]
+			}
+		}
+		return out[0], out[1], out[2]
+	}
+
+	c100, c010, c001 := at(r1, g0, b0), at(r0, g1, b0), at(r0, g0, b1)
+	c110, c101, c011 := at(r1, g1, b0), at(r1, g0, b1), at(r0, g1, b1)
+	for c := range out {
+		c00 := c000[c]*(1-fr) + c100[c]*fr
+		c10 := c010[c]*(1-fr) + c110[c]*fr
+		c01 := c001[c]*(1-fr) + c101[c]*fr
+		c11 := c011[c]*(1-fr) + c111[c]*fr
+		c0 := c00*(1-fg) + c10*fg
+		c1 := c01*(1-fg) + c11*fg
+		out[c] = c0*(1-fb) + c1*fb
+	}
+
+	return out[0], out[1], out[2]
+}
+
+func (ip *ImgProcessor)ApplyLUT(il *ImgLoader, lut *CubeLUT, interp LUTInterpolation) *ImgLoader {
+	src := il.GetMatrix()
+	imgMatrix := NewRGBAMatrix(il.GetMY(), il.GetMX())
+
+	for hi := range imgMatrix {
+		for wi := range imgMatrix[hi] {
+			r, g, b := lut.Lookup(float64(src[hi][wi][0])/255, float64(src[hi][wi][1])/255, float64(src[hi][wi][2])/255, interp)
+			imgMatrix[hi][wi][0] = clampUint8(r * 255)
+			imgMatrix[hi][wi][1] = clampUint8(g * 255)
+			imgMatrix[hi][wi][2] = clampUint8(b * 255)
+			imgMatrix[hi][wi][3] = src[hi][wi][3]
+		}
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: imgMatrix,
+		img: il.GetImg(),
+	}
+}
+
+// 把一串颜色操作烘焙成 size^3 的 3D LUT。
+// 做法是构造一张包含所有格点颜色的图片，依次执行 ops 后读回结果，
+// 因此 ops 只能是逐像素的颜色操作，不能改变图片尺寸或依赖邻域
+func (ip *ImgProcessor)BakeCubeLUT(title string, size int, ops ...func(*ImgLoader) *ImgLoader) *CubeLUT {
+	if size < 2 {
+		size = 2
+	}
+
+	// 第 g + b*size 行、第 r 列为格点 (r, g, b)
+	matrix := NewRGBAMatrix(size*size, size)
+	for b := 0; b < size; b++ {
+		for g := 0; g < size; g++ {
+			for r := 0; r < size; r++ {
+				pixel := matrix[g+b*size][r]
+				pixel[0] = clampUint8(float64(r) * 255 / float64(size-1))
+				pixel[1] = clampUint8(float64(g) * 255 / float64(size-1))
+				pixel[2] = clampUint8(float64(b) * 255 / float64(size-1))
+				pixel[3] = math.MaxUint8
+			}
+		}
+	}
+
+	il := &ImgLoader{filename: title, matrix: matrix}
+	for _, op := range ops {
+		il = op(il)
+	}
+	result := il.GetMatrix()
+
+	lut := &CubeLUT{Title: title, Dimension: 3, Size: size, DomainMax: [3]float64{1, 1, 1}}
+	lut.Table = make([][3]float64, size*size*size)
+	for b := 0; b < size; b++ {
+		for g := 0; g < size; g++ {
+			for r := 0; r < size; r++ {
+				pixel := result[g+b*size][r]
+				lut.Table[r+g*size+b*size*size] = [3]float64{float64(pixel[0]) / 255, float64(pixel[1]) / 255, float64(pixel[2]) / 255}
+			}
+		}
+	}
+
+	return lut
+}
+
+func parseTriple(fields []string) (v [3]float64, err error) {
+	if len(fields) != 3 {
+		return v, fmt.Errorf("expected 3 values, got %d", len(fields))
+	}
+
+	for i, field := range fields {
+		v[i], err = strconv.ParseFloat(field, 64)
+		if err != nil {
+			return v, fmt.Errorf("invalid value %q", field)
+		}
+	}
+
+	return v, nil
+}
@@ -0,0 +1,118 @@
+package tool
+
+import (
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func identityCubeLUT(size int) *CubeLUT {
+	lut := &CubeLUT{Title: "identity", Dimension: 3, Size: size, DomainMax: [3]float64{1, 1, 1}}
+	n := float64(size - 1)
+	for b := 0; b < size; b++ {
+		for g := 0; g < size; g++ {
+			for r := 0; r < size; r++ {
+				lut.Table = append(lut.Table, [3]float64{float64(r) / n, float64(g) / n, float64(b) / n})
+			}
+		}
+	}
+
+	return lut
+}
+
+func TestCubeLUTIdentityRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cube")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "identity.cube")
+	if err = SaveCubeLUT(filename, identityCubeLUT(17)); err != nil {
+		t.Fatal(err)
+	}
+	lut, err := LoadCubeLUT(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lut.Title != "identity" || lut.Dimension != 3 || lut.Size != 17 || len(lut.Table) != 17*17*17 {
+		t.Fatalf("loaded %q dim %d size %d with %d entries", lut.Title, lut.Dimension, lut.Size, len(lut.Table))
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	for _, interp := range []LUTInterpolation{LUTTrilinear, LUTTetrahedral} {
+		for i := 0; i < 1000; i++ {
+			r, g, b := rnd.Float64(), rnd.Float64(), rnd.Float64()
+			or, og, ob := lut.Lookup(r, g, b, interp)
+			if math.Abs(or-r) > 1e-5 || math.Abs(og-g) > 1e-5 || math.Abs(ob-b) > 1e-5 {
+				t.Fatalf("interp %d: Lookup(%v, %v, %v) = (%v, %v, %v)", interp, r, g, b, or, og, ob)
+			}
+		}
+	}
+}
+
+// 通道轮换是线性映射，两种插值都应精确还原；同时检查表的下标顺序
+func TestCubeLUTChannelRotation(t *testing.T) {
+	lut := identityCubeLUT(5)
+	for i, v := range lut.Table {
+		lut.Table[i] = [3]float64{v[2], v[0], v[1]}
+	}
+
+	for _, interp := range []LUTInterpolation{LUTTrilinear, LUTTetrahedral} {
+		or, og, ob := lut.Lookup(0.1, 0.55, 0.9, interp)
+		if math.Abs(or-0.9) > 1e-9 || math.Abs(og-0.1) > 1e-9 || math.Abs(ob-0.55) > 1e-9 {
+			t.Errorf("interp %d: got (%v, %v, %v), want (0.9, 0.1, 0.55)", interp, or, og, ob)
+		}
+	}
+}
+
+func TestApplyIdentityLUT(t *testing.T) {
+	matrix := NewRGBAMatrix(16, 16)
+	for hi := range matrix {
+		for wi := range matrix[hi] {
+			matrix[hi][wi][0], matrix[hi][wi][1], matrix[hi][wi][2], matrix[hi][wi][3] = uint8(hi*16), uint8(wi*16), uint8(hi*wi), uint8(255-hi)
+		}
+	}
+	il := &ImgLoader{filename: "grid", matrix: matrix}
+
+	ip := &ImgProcessor{}
+	for _, interp := range []LUTInterpolation{LUTTrilinear, LUTTetrahedral} {
+		result := ip.ApplyLUT(il, identityCubeLUT(33), interp).GetMatrix()
+		for hi := range matrix {
+			for wi := range matrix[hi] {
+				for c := 0; c < 4; c++ {
+					if result[hi][wi][c] != matrix[hi][wi][c] {
+						t.Fatalf("interp %d: pixel (%d, %d) = %v, want %v", interp, wi, hi, result[hi][wi], matrix[hi][wi])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestLoadCubeLUTErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cube")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := map[string]string{
+		"missing size":  "0 0 0\n1 1 1\n",
+		"wrong count":   "LUT_3D_SIZE 2\n0 0 0\n1 1 1\n",
+		"bad value":     "LUT_1D_SIZE 2\n0 0 x\n1 1 1\n",
+		"mixed 1D & 3D": "LUT_1D_SIZE 2\nLUT_3D_SIZE 2\n",
+	}
+	for name, content := range cases {
+		filename := filepath.Join(dir, "bad.cube")
+		if err = ioutil.WriteFile(filename, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = LoadCubeLUT(filename); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package tool
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 色阶与曲线调整作用的通道，ChannelRGB 为复合通道，同时作用于 R、G、B
+type Channel int
+
+const (
+	ChannelRGB Channel = iota
+	ChannelR
+	ChannelG
+	ChannelB
+)
+
+var channelNames = []string{"rgb", "r", "g", "b"}
+
+func (ch Channel)String() string {
+	if ch < ChannelRGB || ch > ChannelB {
+		return "unknown"
+	}
+
+	return channelNames[ch]
+}
+
+func ParseChannel(name string) (Channel, error) {
+	for i, v := range channelNames {
+		if strings.ToLower(name) == v {
+			return Channel(i), nil
+		}
+	}
+
+	return ChannelRGB, fmt.Errorf("unknown channel: %s", name)
+}
+
+// Photoshop 风格的色阶：输入黑场、白场、中间调伽马以及输出范围
+type Levels struct {
+	InBlack  uint8
+	InWhite  uint8
+	Gamma    float64 // 中间调，1 为不变，大于 1 提亮
+	OutBlack uint8
+	OutWhite uint8
+}
+
+func NewLevels() Levels {
+	return Levels{InBlack: 0, InWhite: 255, Gamma: 1, OutBlack: 0, OutWhite: 255}
+}
+
+func (l Levels)LUT() (lut [256]uint8) {
+	gamma := math.Max(0.1, math.Min(10, l.Gamma))
+	inRange := math.Max(1, float64(l.InWhite)-float64(l.InBlack))
+
+	for i := range lut {
+		v := math.Max(0, math.Min(1, (float64(i)-float64(l.InBlack))/inRange))
+		v = math.Pow(v, 1/gamma)
+		lut[i] = clampUint8(float64(l.OutBlack) + v*(float64(l.OutWhite)-float64(l.OutBlack)))
+	}
+
+	return
+}
+
+func (ip *ImgProcessor)ApplyLevels(il *ImgLoader, ch Channel, l Levels) *ImgLoader {
+	return applyLUT(il, channelLUTs(ch, l.LUT()))
+}
+
+// 曲线控制点，X 为输入值，Y 为输出值，范围均为 [0, 255]
+type CurvePoint struct {
+	X float64
+	Y float64
+}
+
+// 色调曲线，控制点之间使用自然三次样条插值；少于两个点时视为恒等映射
+type Curve []CurvePoint
+
+func (c Curve)LUT() (lut [256]uint8) {
+	points := make(Curve, 0, len(c))
+	for _, p := range c {
+		points = append(points, CurvePoint{X: math.Max(0, math.Min(255, p.X)), Y: math.Max(0, math.Min(255, p.Y))})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].X < points[j].X })
+
+	// X 相同的点只保留最后一个
+	n := 0
+	for _, p := range points {
+		if n > 0 && points[n-1].X == p.X {
+			points[n-1] = p
+			continue
+		}
+		points[n] = p
+		n++
+	}
+	points = points[:n]
+
+	if len(points) < 2 {
+		for i := range lut {
+			lut[i] = uint8(i)
+		}
+		return
+	}
+
+	m := points.secondDerivatives()
+	for i := range lut {
+		x := float64(i)
+		switch {
+		case x <= points[0].X:
+			lut[i] = clampUint8(points[0].Y)
+		case x >= points[n-1].X:
+			lut[i] = clampUint8(points[n-1].Y)
+		default:
+			k := sort.Search(n, func(j int) bool { return points[j].X >= x }) - 1
+			h := points[k+1].X - points[k].X
+			a := (points[k+1].X - x) / h
+			b := (x - points[k].X) / h
+			y := a*points[k].Y + b*points[k+1].Y + ((a*a*a-a)*m[k]+(b*b*b-b)*m[k+1])*h*h/6
+			lut[i] = clampUint8(y)
+		}
+	}
+
+	return
+}
+
+// 自然边界条件下各控制点处的二阶导数，追赶法求解三对角方程组
+func (c Curve)secondDerivatives() []float64 {
+	n := len(c)
+	m := make([]float64, n)
+	u := make([]float64, n)
+
+	for i := 1; i < n-1; i++ {
+		sig := (c[i].X - c[i-1].X) / (c[i+1].X - c[i-1].X)
+		p := sig*m[i-1] + 2
+		m[i] = (sig - 1) / p
+		d := (c[i+1].Y-c[i].Y)/(c[i+1].X-c[i].X) - (c[i].Y-c[i-1].Y)/(c[i].X-c[i-1].X)
+		u[i] = (6*d/(c[i+1].X-c[i-1].X) - sig*u[i-1]) / p
+	}
+
+	m[n-1] = 0
+	for i := n - 2; i >= 0; i-- {
+		m[i] = m[i]*m[i+1] + u[i]
+	}
+
+	return m
+}
+
+func (ip *ImgProcessor)ApplyCurve(il *ImgLoader, ch Channel, c Curve) *ImgLoader {
+	return applyLUT(il, channelLUTs(ch, c.LUT()))
+}
+
+// 曲线预设，每个通道至多一条曲线。
+// 应用时先做各单通道曲线，再做复合通道曲线
+type CurvePreset map[Channel]Curve
+
+func (p CurvePreset)LUTs() (luts [3][256]uint8) {
+	luts = identityLUTs()
+	for c, ch := range []Channel{ChannelR, ChannelG, ChannelB} {
+		if curve, ok := p[ch]; ok {
+			luts[c] = curve.LUT()
+		}
+	}
+
+	if curve, ok := p[ChannelRGB]; ok {
+		composite := curve.LUT()
+		for c := range luts {
+			for i := range luts[c] {
+				luts[c][i] = composite[luts[c][i]]
+			}
+		}
+	}
+
+	return
+}
+
+func (ip *ImgProcessor)ApplyCurvePreset(il *ImgLoader, p CurvePreset) *ImgLoader {
+	return applyLUT(il, p.LUTs())
+}
+
+// 从文本文件读取曲线预设。每行一条曲线，格式为 "通道: x,y x,y ..."，
+// 通道取 rgb、r、g、b，# 开头的行为注释，例如：
+//   rgb: 0,0 64,52 192,204 255,255
+//   b: 0,16 255,240
+func LoadCurvePreset(filename string) (CurvePreset, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	preset := make(CurvePreset)
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d: missing channel", lineNo)
+		}
+		ch, err := ParseChannel(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNo, err.Error())
+		}
+
+		var curve Curve
+		for _, field := range strings.Fields(parts[1]) {
+			xy := strings.Split(field, ",")
+			if len(xy) != 2 {
+				return nil, fmt.Errorf("line %d: invalid point %q", lineNo, field)
+			}
+			x, errX := strconv.ParseFloat(xy[0], 64)
+			y, errY := strconv.ParseFloat(xy[1], 64)
+			if errX != nil || errY != nil {
+				return nil, fmt.Errorf("line %d: invalid point %q", lineNo, field)
+			}
+			curve = append(curve, CurvePoint{X: x, Y: y})
+		}
+		preset[ch] = curve
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(preset) == 0 {
+		return nil, errors.New("empty curve preset")
+	}
+
+	return preset, nil
+}
+
+func SaveCurvePreset(filename string, p CurvePreset) error {
+	var buf bytes.Buffer
+	for _, ch := range []Channel{ChannelRGB, ChannelR, ChannelG, ChannelB} {
+		curve, ok := p[ch]
+		if !ok {
+			continue
+		}
+
+		buf.WriteString(ch.String() + ":")
+		for _, point := range curve {
+			buf.WriteString(" " + strconv.FormatFloat(point.X, 'g', -1, 64) + "," + strconv.FormatFloat(point.Y, 'g', -1, 64))
+		}
+		buf.WriteByte('\n')
+	}
+
+	return ioutil.WriteFile(filename, buf.Bytes(), 0666)
+}
+
+// 将单条查找表放到 ch 对应的通道上，其余通道保持不变
+func channelLUTs(ch Channel, lut [256]uint8) (luts [3][256]uint8) {
+	luts = identityLUTs()
+	switch ch {
+	case ChannelR:
+		luts[0] = lut
+	case ChannelG:
+		luts[1] = lut
+	case ChannelB:
+		luts[2] = lut
+	default:
+		luts = [3][256]uint8{lut, lut, lut}
+	}
+
+	return
+}
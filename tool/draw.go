@@ -0,0 +1,445 @@
+package tool
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/vector"
+)
+
+// 绘图样式。Fill 为 true 时填充图形，否则以 Width 为线宽描边，颜色的 A 分量即不透明度
+type DrawStyle struct {
+	Color color.RGBA
+	Width float64
+	Fill  bool
+}
+
+// 坐标均以像素中心为整数点，与 Point 的约定一致
+
+func (ip *ImgProcessor)DrawLine(il *ImgLoader, p0, p1 Point, style DrawStyle) *ImgLoader {
+	return drawOn(il, func(canvas *drawCanvas) {
+		canvas.polyline([]Point{p0, p1}, false, style)
+	})
+}
+
+// 矩形覆盖左上角为 (x, y) 的 w x h 个像素，描边画在矩形内侧
+func (ip *ImgProcessor)DrawRect(il *ImgLoader, x, y, w, h float64, style DrawStyle) *ImgLoader {
+	return ip.DrawRoundedRect(il, x, y, w, h, 0, style)
+}
+
+func (ip *ImgProcessor)DrawRoundedRect(il *ImgLoader, x, y, w, h, radius float64, style DrawStyle) *ImgLoader {
+	return drawOn(il, func(canvas *drawCanvas) {
+		canvas.roundedRect(x, y, w, h, radius, style)
+	})
+}
+
+func (ip *ImgProcessor)DrawCircle(il *ImgLoader, center Point, r float64, style DrawStyle) *ImgLoader {
+	return ip.DrawEllipse(il, center, r, r, style)
+}
+
+// 描边以椭圆轮廓为中线
+func (ip *ImgProcessor)DrawEllipse(il *ImgLoader, center Point, rx, ry float64, style DrawStyle) *ImgLoader {
+	return drawOn(il, func(canvas *drawCanvas) {
+		canvas.ellipse(center, rx, ry, style)
+	})
+}
+
+// 闭合多边形，填充时自相交的部分按非零环绕规则处理
+func (ip *ImgProcessor)DrawPolygon(il *ImgLoader, points []Point, style DrawStyle) *ImgLoader {
+	return drawOn(il, func(canvas *drawCanvas) {
+		canvas.polyline(points, true, style)
+	})
+}
+
+// 不闭合的折线，只描边
+func (ip *ImgProcessor)DrawPolyline(il *ImgLoader, points []Point, style DrawStyle) *ImgLoader {
+	style.Fill = false
+	return drawOn(il, func(canvas *drawCanvas) {
+		canvas.polyline(points, false, style)
+	})
+}
+
+// 从 (x, y) 开始把四连通且与起点颜色相近（各通道差值不超过 tolerance）的区域涂成 c
+func (ip *ImgProcessor)FloodFill(il *ImgLoader, x, y int, c color.RGBA, tolerance uint8) *ImgLoader {
+	return drawOn(il, func(canvas *drawCanvas) {
+		canvas.floodFill(x, y, c, tolerance)
+	})
+}
+
+// 一条绘图命令，Args 的含义由 Shape 决定：
+//	line x0 y0 x1 y1
+//	rect x y w h
+//	roundrect x y w h radius
+//	circle cx cy r
+//	ellipse cx cy rx ry
+//	polygon x0 y0 x1 y1 ...
+//	polyline x0 y0 x1 y1 ...
+//	floodfill x y
+type DrawCommand struct {
+	Shape     string
+	Args      []float64
+	Style     DrawStyle
+	Tolerance uint8 // 只用于 floodfill
+}
+
+var drawArgCounts = map[string]int{
+	"line": 4, "rect": 4, "roundrect": 5, "circle": 3, "ellipse": 4, "polygon": -1, "polyline": -1, "floodfill": 2,
+}
+
+// 读取绘图命令文件，每行一条命令，形如 "rect 10 10 120 80 color=ff0000cc width=3"。
+// 可选参数有 color=rrggbb[aa]（默认不透明红色）、width=线宽（默认 1）、fill、tolerance=容差，# 开头的行为注释
+func LoadDrawCommands(filename string) ([]DrawCommand, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var commands []DrawCommand
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		cmd := DrawCommand{
+			Shape: strings.ToLower(fields[0]),
+			Style: DrawStyle{Color: color.RGBA{R: 255, A: 255}, Width: 1},
+		}
+		count, ok := drawArgCounts[cmd.Shape]
+		if !ok {
+			return nil, fmt.Errorf("line %d: unknown shape %q", lineNo, fields[0])
+		}
+
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) == 1 && strings.ToLower(kv[0]) == "fill" {
+				cmd.Style.Fill = true
+				continue
+			}
+			if len(kv) == 1 {
+				v, err := strconv.ParseFloat(field, 64)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid number %q", lineNo, field)
+				}
+				cmd.Args = append(cmd.Args, v)
+				continue
+			}
+
+			switch strings.ToLower(kv[0]) {
+			case "color":
+				if cmd.Style.Color, err = ParseHexColor(kv[1]); err != nil {
+					return nil, fmt.Errorf("line %d: %s", lineNo, err.Error())
+				}
+			case "width":
+				if cmd.Style.Width, err = strconv.ParseFloat(kv[1], 64); err != nil {
+					return nil, fmt.Errorf("line %d: invalid width %q", lineNo, kv[1])
+				}
+			case "tolerance":
+				t, err := strconv.ParseUint(kv[1], 10, 8)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid tolerance %q", lineNo, kv[1])
+				}
+				cmd.Tolerance = uint8(t)
+			default:
+				return nil, fmt.Errorf("line %d: unknown option %q", lineNo, kv[0])
+			}
+		}
+
+		if count < 0 && (len(cmd.Args) < 4 || len(cmd.Args)%2 != 0) {
+			return nil, fmt.Errorf("line %d: %s needs at least 2 points", lineNo, cmd.Shape)
+		} else if count >= 0 && len(cmd.Args) != count {
+			return nil, fmt.Errorf("line %d: %s needs %d numbers", lineNo, cmd.Shape, count)
+		}
+		commands = append(commands, cmd)
+	}
+
+	return commands, scanner.Err()
+}
+
+// 按顺序执行所有绘图命令
+func (ip *ImgProcessor)ApplyDrawCommands(il *ImgLoader, commands []DrawCommand) *ImgLoader {
+	return drawOn(il, func(canvas *drawCanvas) {
+		for _, cmd := range commands {
+			a := cmd.Args
+			switch cmd.Shape {
+			case "line":
+				canvas.polyline([]Point{{a[0], a[1]}, {a[2], a[3]}}, false, cmd.Style)
+			case "rect":
+				canvas.roundedRect(a[0], a[1], a[2], a[3], 0, cmd.Style)
+			case "roundrect":
+				canvas.roundedRect(a[0], a[1], a[2], a[3], a[4], cmd.Style)
+			case "circle":
+				canvas.ellipse(Point{a[0], a[1]}, a[2], a[2], cmd.Style)
+			case "ellipse":
+				canvas.ellipse(Point{a[0], a[1]}, a[2], a[3], cmd.Style)
+			case "polygon", "polyline":
+				points := make([]Point, len(a)/2)
+				for i := range points {
+					points[i] = Point{a[2*i], a[2*i+1]}
+				}
+				style := cmd.Style
+				if cmd.Shape == "polyline" {
+					style.Fill = false
+				}
+				canvas.polyline(points, cmd.Shape == "polygon", style)
+			case "floodfill":
+				canvas.floodFill(int(a[0]), int(a[1]), cmd.Style.Color, cmd.Tolerance)
+			}
+		}
+	})
+}
+
+// 在 il 的副本上作画
+func drawOn(il *ImgLoader, paint func(canvas *drawCanvas)) *ImgLoader {
+	canvas := &drawCanvas{
+		matrix: il.GetMatrix(),
+		z: vector.NewRasterizer(il.GetMX(), il.GetMY()),
+	}
+	paint(canvas)
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: canvas.matrix,
+		img: il.GetImg(),
+	}
+}
+
+// 用抗锯齿光栅化器得到图形的覆盖率蒙版，再按颜色合成到矩阵上。
+// 光栅化器以像素左上角为原点，因此像素中心坐标要加 0.5
+type drawCanvas struct {
+	matrix [][][]uint8
+	z      *vector.Rasterizer
+}
+
+func (canvas *drawCanvas)begin() {
+	size := canvas.z.Size()
+	canvas.z.Reset(size.X, size.Y)
+}
+
+func (canvas *drawCanvas)moveTo(x, y float64) {
+	canvas.z.MoveTo(float32(x+0.5), float32(y+0.5))
+}
+
+func (canvas *drawCanvas)lineTo(x, y float64) {
+	canvas.z.LineTo(float32(x+0.5), float32(y+0.5))
+}
+
+func (canvas *drawCanvas)cubeTo(x1, y1, x2, y2, x, y float64) {
+	canvas.z.CubeTo(float32(x1+0.5), float32(y1+0.5), float32(x2+0.5), float32(y2+0.5), float32(x+0.5), float32(y+0.5))
+}
+
+// 把当前路径光栅化后以颜色 c 合成，多段路径叠加在同一个蒙版上
+func (canvas *drawCanvas)paint(c color.RGBA, pieces ...func()) {
+	size := canvas.z.Size()
+	mask := image.NewAlpha(image.Rect(0, 0, size.X, size.Y))
+	for _, piece := range pieces {
+		canvas.begin()
+		piece()
+		canvas.z.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+	}
+	fillMask(canvas.matrix, mask, 0, 0, c)
+}
+
+// 折线或多边形。描边由每段线段的矩形和拐点处的圆拼成，因此线宽处处一致且转角为圆角
+func (canvas *drawCanvas)polyline(points []Point, closed bool, style DrawStyle) {
+	if len(points) < 2 {
+		return
+	}
+
+	if style.Fill && closed {
+		canvas.paint(style.Color, func() {
+			canvas.moveTo(points[0].X, points[0].Y)
+			for _, p := range points[1:] {
+				canvas.lineTo(p.X, p.Y)
+			}
+			canvas.z.ClosePath()
+		})
+		return
+	}
+
+	half := math.Max(style.Width, 0.5) / 2
+	segments := len(points) - 1
+	if closed {
+		segments++
+	}
+
+	var pieces []func()
+	for i := 0; i < segments; i++ {
+		p0, p1 := points[i], points[(i+1)%len(points)]
+		pieces = append(pieces, func() {
+			canvas.segment(p0, p1, half)
+		})
+	}
+	if half > 1 {
+		for i, p := range points {
+			if !closed && (i == 0 || i == len(points)-1) {
+				continue
+			}
+			center := p
+			pieces = append(pieces, func() {
+				canvas.ellipsePath(center, half, half, false)
+			})
+		}
+	}
+	canvas.paint(style.Color, pieces...)
+}
+
+// 以 p0、p1 为中线、半宽为 half 的矩形
+func (canvas *drawCanvas)segment(p0, p1 Point, half float64) {
+	dx, dy := p1.X-p0.X, p1.Y-p0.Y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		canvas.ellipsePath(p0, half, half, false)
+		return
+	}
+	nx, ny := -dy/length*half, dx/length*half
+
+	canvas.moveTo(p0.X+nx, p0.Y+ny)
+	canvas.lineTo(p1.X+nx, p1.Y+ny)
+	canvas.lineTo(p1.X-nx, p1.Y-ny)
+	canvas.lineTo(p0.X-nx, p0.Y-ny)
+	canvas.z.ClosePath()
+}
+
+func (canvas *drawCanvas)roundedRect(x, y, w, h, radius float64, style DrawStyle) {
+	if w <= 0 || h <= 0 {
+		return
+	}
+
+	// 换算为像素边界：像素 x 的范围是 [x-0.5, x+0.5]
+	left, top, right, bottom := x-0.5, y-0.5, x+w-0.5, y+h-0.5
+	if style.Fill {
+		canvas.paint(style.Color, func() {
+			canvas.roundedRectPath(left, top, right, bottom, radius, false)
+		})
+		return
+	}
+
+	// 外轮廓顺时针、内轮廓逆时针，两者相减得到环形的边框
+	width := math.Max(style.Width, 0.5)
+	canvas.paint(style.Color, func() {
+		canvas.roundedRectPath(left, top, right, bottom, radius, false)
+		if 2*width < w && 2*width < h {
+			canvas.roundedRectPath(left+width, top+width, right-width, bottom-width, math.Max(0, radius-width), true)
+		}
+	})
+}
+
+// 圆角用三次贝塞尔曲线近似四分之一圆
+func (canvas *drawCanvas)roundedRectPath(left, top, right, bottom, r float64, reverse bool) {
+	r = math.Min(r, math.Min(right-left, bottom-top)/2)
+	k := r * (1 - kappa)
+
+	type corner struct{ x, y, x1, y1, x2, y2, ex, ey float64 }
+	path := []corner{
+		{right - r, top, right - k, top, right, top + k, right, top + r},
+		{right, bottom - r, right, bottom - k, right - k, bottom, right - r, bottom},
+		{left + r, bottom, left + k, bottom, left, bottom - k, left, bottom - r},
+		{left, top + r, left, top + k, left + k, top, left + r, top},
+	}
+	if reverse {
+		reversed := make([]corner, len(path))
+		for i, c := range path {
+			reversed[len(path)-1-i] = corner{c.ex, c.ey, c.x2, c.y2, c.x1, c.y1, c.x, c.y}
+		}
+		path = reversed
+	}
+
+	canvas.moveTo(path[0].x, path[0].y)
+	for _, c := range path {
+		canvas.lineTo(c.x, c.y)
+		if r > 0 {
+			canvas.cubeTo(c.x1, c.y1, c.x2, c.y2, c.ex, c.ey)
+		}
+	}
+	canvas.z.ClosePath()
+}
+
+func (canvas *drawCanvas)ellipse(center Point, rx, ry float64, style DrawStyle) {
+	if style.Fill {
+		canvas.paint(style.Color, func() {
+			canvas.ellipsePath(center, rx, ry, false)
+		})
+		return
+	}
+
+	half := math.Max(style.Width, 0.5) / 2
+	canvas.paint(style.Color, func() {
+		canvas.ellipsePath(center, rx+half, ry+half, false)
+		if rx > half && ry > half {
+			canvas.ellipsePath(center, rx-half, ry-half, true)
+		}
+	})
+}
+
+// 用于以三次贝塞尔曲线近似四分之一圆的控制点系数
+const kappa = 0.5522847498
+
+func (canvas *drawCanvas)ellipsePath(c Point, rx, ry float64, reverse bool) {
+	kx, ky := rx*kappa, ry*kappa
+	if reverse {
+		ky = -ky
+		ry = -ry
+	}
+
+	canvas.moveTo(c.X+rx, c.Y)
+	canvas.cubeTo(c.X+rx, c.Y+ky, c.X+kx, c.Y+ry, c.X, c.Y+ry)
+	canvas.cubeTo(c.X-kx, c.Y+ry, c.X-rx, c.Y+ky, c.X-rx, c.Y)
+	canvas.cubeTo(c.X-rx, c.Y-ky, c.X-kx, c.Y-ry, c.X, c.Y-ry)
+	canvas.cubeTo(c.X+kx, c.Y-ry, c.X+rx, c.Y-ky, c.X+rx, c.Y)
+	canvas.z.ClosePath()
+}
+
+func (canvas *drawCanvas)floodFill(x, y int, c color.RGBA, tolerance uint8) {
+	matrix := canvas.matrix
+	if y < 0 || y >= len(matrix) || x < 0 || x >= len(matrix[0]) {
+		return
+	}
+
+	var seed [4]uint8
+	copy(seed[:], matrix[y][x])
+	similar := func(p []uint8) bool {
+		for ch := 0; ch < 4; ch++ {
+			d := int(p[ch]) - int(seed[ch])
+			if d > int(tolerance) || -d > int(tolerance) {
+				return false
+			}
+		}
+		return true
+	}
+
+	visited := make([][]bool, len(matrix))
+	for hi := range visited {
+		visited[hi] = make([]bool, len(matrix[hi]))
+	}
+
+	src := []uint8{c.R, c.G, c.B, c.A}
+	stack := []image.Point{{X: x, Y: y}}
+	visited[y][x] = true
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		blendPixel(matrix[p.Y][p.X], src, BlendNormal, 1)
+
+		for _, n := range []image.Point{{X: p.X - 1, Y: p.Y}, {X: p.X + 1, Y: p.Y}, {X: p.X, Y: p.Y - 1}, {X: p.X, Y: p.Y + 1}} {
+			if n.Y < 0 || n.Y >= len(matrix) || n.X < 0 || n.X >= len(matrix[n.Y]) || visited[n.Y][n.X] {
+				continue
+			}
+			if similar(matrix[n.Y][n.X]) {
+				visited[n.Y][n.X] = true
+				stack = append(stack, n)
+			}
+		}
+	}
+}
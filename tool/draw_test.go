@@ -0,0 +1,179 @@
+package tool
+
+import (
+	"image/color"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var drawRed = color.RGBA{R: 255, A: 255}
+
+func TestDrawFillCoverage(t *testing.T) {
+	ip := &ImgProcessor{}
+
+	// 整数坐标的矩形恰好覆盖 w x h 个像素，没有抗锯齿的边缘
+	matrix := ip.DrawRect(blankImage(20, 20), 2, 3, 10, 5, DrawStyle{Color: drawRed, Fill: true}).GetMatrix()
+	for hi := range matrix {
+		for wi := range matrix[hi] {
+			want := uint8(0)
+			if hi >= 3 && hi < 8 && wi >= 2 && wi < 12 {
+				want = 255
+			}
+			if matrix[hi][wi][0] != want {
+				t.Fatalf("rect: pixel (%d, %d) = %d, want %d", hi, wi, matrix[hi][wi][0], want)
+			}
+		}
+	}
+
+	matrix = ip.DrawCircle(blankImage(41, 41), Point{X: 20, Y: 20}, 10, DrawStyle{Color: drawRed, Fill: true}).GetMatrix()
+	var area float64
+	for hi := range matrix {
+		for wi := range matrix[hi] {
+			v := matrix[hi][wi][0]
+			area += float64(v) / 255
+			d := math.Hypot(float64(wi-20), float64(hi-20))
+			if d < 9 && v != 255 || d > 11 && v != 0 {
+				t.Fatalf("circle: pixel (%d, %d) at distance %.2f = %d", hi, wi, d, v)
+			}
+		}
+	}
+	if want := math.Pi * 100; math.Abs(area-want) > want*0.02 {
+		t.Errorf("circle covers %.1f pixels, want about %.1f", area, want)
+	}
+}
+
+// 描边由外轮廓减去反向的内轮廓得到，内轮廓方向错误时中间会被填满
+func TestDrawStrokeIsHollow(t *testing.T) {
+	ip := &ImgProcessor{}
+	style := DrawStyle{Color: drawRed, Width: 3}
+
+	matrix := ip.DrawEllipse(blankImage(41, 31), Point{X: 20, Y: 15}, 15, 10, style).GetMatrix()
+	for _, p := range [][2]int{{15, 20}, {15, 10}, {10, 20}, {15, 30}} {
+		if v := matrix[p[0]][p[1]][0]; v != 0 {
+			t.Errorf("ellipse: inner pixel %v = %d, want 0", p, v)
+		}
+	}
+	for _, p := range [][2]int{{15, 5}, {15, 35}, {5, 20}, {25, 20}} {
+		if v := matrix[p[0]][p[1]][0]; v != 255 {
+			t.Errorf("ellipse: outline pixel %v = %d, want 255", p, v)
+		}
+	}
+
+	// 覆盖第 2 到 31 列、第 2 到 21 行，边框宽 3 像素
+	matrix = ip.DrawRoundedRect(blankImage(40, 30), 2, 2, 30, 20, 6, style).GetMatrix()
+	for _, p := range [][2]int{{12, 17}, {12, 5}, {5, 17}, {8, 8}} {
+		if v := matrix[p[0]][p[1]][0]; v != 0 {
+			t.Errorf("rounded rect: inner pixel %v = %d, want 0", p, v)
+		}
+	}
+	for _, p := range [][2]int{{12, 2}, {12, 4}, {12, 31}, {2, 17}, {21, 17}} {
+		if v := matrix[p[0]][p[1]][0]; v != 255 {
+			t.Errorf("rounded rect: border pixel %v = %d, want 255", p, v)
+		}
+	}
+	if v := matrix[2][2][0]; v > 128 {
+		t.Errorf("rounded rect: corner pixel = %d, want mostly uncovered", v)
+	}
+}
+
+func TestFloodFillStopsAtBoundary(t *testing.T) {
+	// 白底上 2 到 7 行、列的黑色方框
+	il := blankImage(10, 10)
+	for hi := range il.matrix {
+		for wi := range il.matrix[hi] {
+			onRing := (hi == 2 || hi == 7) && wi >= 2 && wi <= 7 || (wi == 2 || wi == 7) && hi >= 2 && hi <= 7
+			if !onRing {
+				il.matrix[hi][wi][0], il.matrix[hi][wi][1], il.matrix[hi][wi][2] = 255, 255, 255
+			}
+		}
+	}
+
+	matrix := new(ImgProcessor).FloodFill(il, 4, 4, drawRed, 10).GetMatrix()
+	for hi := range matrix {
+		for wi := range matrix[hi] {
+			p := matrix[hi][wi]
+			switch {
+			case hi > 2 && hi < 7 && wi > 2 && wi < 7:
+				if p[0] != 255 || p[1] != 0 {
+					t.Fatalf("inside pixel (%d, %d) = %v, want red", hi, wi, p)
+				}
+			case hi >= 2 && hi <= 7 && wi >= 2 && wi <= 7:
+				if p[0] != 0 {
+					t.Fatalf("boundary pixel (%d, %d) = %v, want black", hi, wi, p)
+				}
+			default:
+				if p[0] != 255 || p[1] != 255 {
+					t.Fatalf("outside pixel (%d, %d) = %v, want white", hi, wi, p)
+				}
+			}
+		}
+	}
+}
+
+func writeDrawCommands(t *testing.T, dir, content string) string {
+	filename := filepath.Join(dir, "commands.txt")
+	if err := ioutil.WriteFile(filename, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	return filename
+}
+
+func TestLoadDrawCommands(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	filename := writeDrawCommands(t, dir, "# comment\n\nRECT 2 3 10 5 fill color=00ff00\npolyline 0 0 5 5 9 0 width=2.5\nfloodfill 0 0 tolerance=12\n")
+	commands, err := LoadDrawCommands(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 3 {
+		t.Fatalf("got %d commands, want 3", len(commands))
+	}
+	if c := commands[0]; c.Shape != "rect" || len(c.Args) != 4 || !c.Style.Fill || c.Style.Color != (color.RGBA{G: 255, A: 255}) {
+		t.Errorf("rect = %+v", c)
+	}
+	if c := commands[1]; c.Shape != "polyline" || len(c.Args) != 6 || c.Style.Width != 2.5 || c.Style.Color != drawRed {
+		t.Errorf("polyline = %+v", c)
+	}
+	if c := commands[2]; c.Tolerance != 12 {
+		t.Errorf("floodfill tolerance = %d, want 12", c.Tolerance)
+	}
+
+	matrix := new(ImgProcessor).ApplyDrawCommands(blankImage(20, 20), commands[:1]).GetMatrix()
+	if p := matrix[5][5]; p[1] != 255 || p[0] != 0 {
+		t.Errorf("pixel inside the rect = %v, want green", p)
+	}
+}
+
+func TestLoadDrawCommandsErrors(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	lines := []string{
+		"triangle 0 0 1 1",
+		"rect 1 2 3",
+		"rect 1 2 3 4 5",
+		"circle 1 2 x",
+		"polygon 0 0 1",
+		"polyline 0 0",
+		"line 0 0 1 1 color=zz0000",
+		"line 0 0 1 1 width=thick",
+		"floodfill 0 0 tolerance=300",
+		"line 0 0 1 1 dash=2",
+	}
+	for _, line := range lines {
+		filename := writeDrawCommands(t, dir, "line 0 0 1 1\n"+line+"\n")
+		_, err := LoadDrawCommands(filename)
+		if err == nil {
+			t.Errorf("%q: expected an error", line)
+		} else if !strings.HasPrefix(err.Error(), "line 2:") {
+			t.Errorf("%q: error %q does not name line 2", line, err.Error())
+		}
+	}
+}
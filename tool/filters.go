@@ -0,0 +1,229 @@
+package tool
+
+import (
+	"image/color"
+	"math"
+)
+
+// 带名字的滤镜，供滤镜预览图等批量场景使用
+type NamedFilter struct {
+	Name  string
+	Apply func(il *ImgLoader) *ImgLoader
+}
+
+// 怀旧（棕褐色），intensity 范围 [0, 1]
+func (ip *ImgProcessor)Sepia(il *ImgLoader, intensity float64) *ImgLoader {
+	intensity = math.Max(0, math.Min(1, intensity))
+
+	return mapPixels(il, func(r, g, b float64) (float64, float64, float64) {
+		sr := 0.393*r + 0.769*g + 0.189*b
+		sg := 0.349*r + 0.686*g + 0.168*b
+		sb := 0.272*r + 0.534*g + 0.131*b
+		return r + (sr-r)*intensity, g + (sg-g)*intensity, b + (sb-b)*intensity
+	})
+}
+
+// 复古褪色：抬高黑位、压低白位、降低饱和度并略微偏暖，fade 范围 [0, 1]
+func (ip *ImgProcessor)Vintage(il *ImgLoader, fade float64) *ImgLoader {
+	fade = math.Max(0, math.Min(1, fade))
+
+	levels := NewLevels()
+	levels.OutBlack = clampUint8(60 * fade)
+	levels.OutWhite = clampUint8(255 - 30*fade)
+	faded := ip.ApplyLevels(ip.AdjustSaturation(il, -0.4*fade), ChannelRGB, levels)
+
+	return applyGains(faded, [3]float64{1 + 0.08*fade, 1, 1 - 0.15*fade})
+}
+
+// 交叉冲印：R、G 通道加强对比，B 通道降低对比并抬高暗部，呈现偏黄绿的高光与偏蓝的阴影
+func (ip *ImgProcessor)CrossProcess(il *ImgLoader) *ImgLoader {
+	return ip.ApplyCurvePreset(il, CurvePreset{
+		ChannelR: Curve{{0, 0}, {88, 47}, {170, 188}, {255, 255}},
+		ChannelG: Curve{{0, 0}, {65, 57}, {190, 208}, {255, 255}},
+		ChannelB: Curve{{0, 40}, {255, 215}},
+	})
+}
+
+// 双色调：按亮度在 dark 与 light 两种颜色之间插值
+func (ip *ImgProcessor)Duotone(il *ImgLoader, dark, light color.RGBA) *ImgLoader {
+	return mapPixels(il, func(r, g, b float64) (float64, float64, float64) {
+		t := (r*30 + g*59 + b*11) / 100 / 255
+		return float64(dark.R) + (float64(light.R)-float64(dark.R))*t,
+			float64(dark.G) + (float64(light.G)-float64(dark.G))*t,
+			float64(dark.B) + (float64(light.B)-float64(dark.B))*t
+	})
+}
+
+// 曝光过度（Sabattier 效应）：高于 threshold 的通道值取反
+func (ip *ImgProcessor)Solarize(il *ImgLoader, threshold uint8) *ImgLoader {
+	var lut [256]uint8
+	for i := range lut {
+		lut[i] = uint8(i)
+		if uint8(i) > threshold {
+			lut[i] = math.MaxUint8 - uint8(i)
+		}
+	}
+
+	return applyLUT(il, [3][256]uint8{lut, lut, lut})
+}
+
+// 色调分离：每个通道量化为 levels 级，范围 [2, 64]
+func (ip *ImgProcessor)Posterize(il *ImgLoader, levels int) *ImgLoader {
+	levels = clampInt(levels, 2, 64)
+
+	var lut [256]uint8
+	for i := range lut {
+		step := math.Floor(float64(i) * float64(levels) / 256)
+		lut[i] = clampUint8(step * 255 / float64(levels-1))
+	}
+
+	return applyLUT(il, [3][256]uint8{lut, lut, lut})
+}
+
+// 浮雕：对亮度做方向性差分后加上中灰，strength 范围 [0.1, 5]
+func (ip *ImgProcessor)Emboss(il *ImgLoader, strength float64) *ImgLoader {
+	strength = math.Max(0.1, math.Min(5, strength))
+	kernel := [3][3]float64{{-2, -1, 0}, {-1, 0, 1}, {0, 1, 2}}
+
+	src := il.GetMatrix()
+	gray := grayPlane(src)
+	height, width := il.GetMY(), il.GetMX()
+	imgMatrix := NewRGBAMatrix(height, width)
+
+	for hi := range imgMatrix {
+		for wi := range imgMatrix[hi] {
+			var v float64
+			for ky := 0; ky < 3; ky++ {
+				for kx := 0; kx < 3; kx++ {
+					v += kernel[ky][kx] * gray[clampInt(hi+ky-1, 0, height-1)][clampInt(wi+kx-1, 0, width-1)]
+				}
+			}
+			g := clampUint8(128 + v*strength/4)
+			imgMatrix[hi][wi][0], imgMatrix[hi][wi][1], imgMatrix[hi][wi][2] = g, g, g
+			imgMatrix[hi][wi][3] = src[hi][wi][3]
+		}
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: imgMatrix,
+		img: il.GetImg(),
+	}
+}
+
+// 暗角：以图片中心为圆心按椭圆距离逐渐压暗。
+// strength 范围 [0, 1]，为边角压暗的比例；radius 范围 [0, 0.9]，为开始变暗的相对半径（中心为 0，边角为 1）
+func (ip *ImgProcessor)Vignette(il *ImgLoader, strength, radius float64) *ImgLoader {
+	strength = math.Max(0, math.Min(1, strength))
+	radius = math.Max(0, math.Min(0.9, radius))
+
+	src := il.GetMatrix()
+	height, width := il.GetMY(), il.GetMX()
+	cy, cx := float64(height)/2, float64(width)/2
+	imgMatrix := NewRGBAMatrix(height, width)
+
+	for hi := range imgMatrix {
+		for wi := range imgMatrix[hi] {
+			dy, dx := (float64(hi)+0.5-cy)/cy, (float64(wi)+0.5-cx)/cx
+			// 归一化后边角处的距离为 sqrt(2)
+			d := math.Hypot(dx, dy) / math.Sqrt2
+			t := math.Max(0, math.Min(1, (d-radius)/(1-radius)))
+			k := 1 - strength*t*t*(3-2*t)
+			for c := 0; c < 3; c++ {
+				imgMatrix[hi][wi][c] = clampUint8(float64(src[hi][wi][c]) * k)
+			}
+			imgMatrix[hi][wi][3] = src[hi][wi][3]
+		}
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: imgMatrix,
+		img: il.GetImg(),
+	}
+}
+
+// 滤镜预览图使用的默认滤镜及参数
+func (ip *ImgProcessor)DefaultFilters() []NamedFilter {
+	return []NamedFilter{
+		{"Original", func(il *ImgLoader) *ImgLoader { return il }},
+		{"Sunset", ip.SunsetEffect},
+		{"NegativeFilm", ip.NegativeFilmEffect},
+		{"Gray", ip.RGB2Gray},
+		{"Sepia", func(il *ImgLoader) *ImgLoader { return ip.Sepia(il, 1) }},
+		{"Vintage", func(il *ImgLoader) *ImgLoader { return ip.Vintage(il, 0.7) }},
+		{"CrossProcess", ip.CrossProcess},
+		{"Duotone", func(il *ImgLoader) *ImgLoader {
+			return ip.Duotone(il, color.RGBA{R: 30, G: 20, B: 90, A: 255}, color.RGBA{R: 255, G: 200, B: 120, A: 255})
+		}},
+		{"Solarize", func(il *ImgLoader) *ImgLoader { return ip.Solarize(il, 128) }},
+		{"Posterize", func(il *ImgLoader) *ImgLoader { return ip.Posterize(il, 4) }},
+		{"Threshold", func(il *ImgLoader) *ImgLoader {
+			mask, _ := ip.Binarize(il, ThresholdOtsu, 0)
+			return mask
+		}},
+		{"Emboss", func(il *ImgLoader) *ImgLoader { return ip.Emboss(il, 1) }},
+		{"Vignette", func(il *ImgLoader) *ImgLoader { return ip.Vignette(il, 0.8, 0.4) }},
+	}
+}
+
+// 滤镜预览图：把 il 缩放到宽 cellWidth 后依次应用 filters，按 cols 列平铺，格子之间留 4 像素白边
+func (ip *ImgProcessor)ContactSheet(il *ImgLoader, filters []NamedFilter, cellWidth, cols int) *ImgLoader {
+	const gap = 4
+	if cellWidth < 16 {
+		cellWidth = 16
+	}
+	if cols < 1 {
+		cols = 1
+	}
+	cellHeight := int(math.Max(1, math.Round(float64(cellWidth)*float64(il.GetMY())/float64(il.GetMX()))))
+	thumb := ip.Resize(il, cellHeight, cellWidth)
+
+	rows := (len(filters) + cols - 1) / cols
+	sheet := NewRGBAMatrix(rows*(cellHeight+gap)+gap, cols*(cellWidth+gap)+gap)
+	for hi := range sheet {
+		for wi := range sheet[hi] {
+			sheet[hi][wi][0], sheet[hi][wi][1], sheet[hi][wi][2], sheet[hi][wi][3] = 255, 255, 255, 255
+		}
+	}
+
+	for i, filter := range filters {
+		cell := filter.Apply(thumb).GetMatrix()
+		top, left := gap+(i/cols)*(cellHeight+gap), gap+(i%cols)*(cellWidth+gap)
+		for hi := range cell {
+			for wi := range cell[hi] {
+				copy(sheet[top+hi][left+wi], cell[hi][wi])
+			}
+		}
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: sheet,
+		img: il.GetImg(),
+	}
+}
+
+// 逐像素映射 RGB，fn 的输入输出范围为 [0, 255]
+func mapPixels(il *ImgLoader, fn func(r, g, b float64) (float64, float64, float64)) *ImgLoader {
+	src := il.GetMatrix()
+	imgMatrix := NewRGBAMatrix(il.GetMY(), il.GetMX())
+
+	for hi := range imgMatrix {
+		for wi := range imgMatrix[hi] {
+			r, g, b := fn(float64(src[hi][wi][0]), float64(src[hi][wi][1]), float64(src[hi][wi][2]))
+			imgMatrix[hi][wi][0], imgMatrix[hi][wi][1], imgMatrix[hi][wi][2] = clampUint8(r), clampUint8(g), clampUint8(b)
+			imgMatrix[hi][wi][3] = src[hi][wi][3]
+		}
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: imgMatrix,
+		img: il.GetImg(),
+	}
+}
@@ -0,0 +1,462 @@
+package tool
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+// 感知哈希值，任意位数，按位从高到低依次存放在 uint64 中
+type Hash struct {
+	words []uint64
+	size  int
+}
+
+func newHash(bitList []bool) Hash {
+	h := Hash{words: make([]uint64, (len(bitList)+63)/64), size: len(bitList)}
+	for i, b := range bitList {
+		if b {
+			h.words[i/64] |= 1 << uint(63-i%64)
+		}
+	}
+
+	return h
+}
+
+// 位数
+func (h Hash)Len() int {
+	return h.size
+}
+
+func (h Hash)Bit(i int) bool {
+	return h.words[i/64]&(1<<uint(63-i%64)) != 0
+}
+
+// 不超过 64 位时的紧凑表示，第一位为最高位；超过 64 位时只返回前 64 位
+func (h Hash)Uint64() uint64 {
+	if h.size == 0 {
+		return 0
+	}
+	if h.size < 64 {
+		return h.words[0] >> uint(64-h.size)
+	}
+
+	return h.words[0]
+}
+
+// 十六进制表示，位数不是 4 的倍数时在末尾补 0
+func (h Hash)Hex() string {
+	var sb strings.Builder
+	for i := 0; i < h.size; i += 4 {
+		var nibble uint
+		for j := 0; j < 4; j++ {
+			nibble <<= 1
+			if i+j < h.size && h.Bit(i+j) {
+				nibble |= 1
+			}
+		}
+		sb.WriteByte("0123456789abcdef"[nibble])
+	}
+
+	return sb.String()
+}
+
+func (h Hash)String() string {
+	return h.Hex()
+}
+
+// 由十六进制串还原哈希，size 为原始位数，不大于 0 时取 4 * len(s)
+func ParseHash(s string, size int) (Hash, error) {
+	if size <= 0 {
+		size = 4 * len(s)
+	}
+	if (size+3)/4 != len(s) {
+		return Hash{}, fmt.Errorf("hash %q does not have %d bits", s, size)
+	}
+
+	bitList := make([]bool, size)
+	for i, c := range strings.ToLower(s) {
+		v := strings.IndexRune("0123456789abcdef", c)
+		if v < 0 {
+			return Hash{}, fmt.Errorf("invalid hex hash: %s", s)
+		}
+		for j := 0; j < 4 && 4*i+j < size; j++ {
+			bitList[4*i+j] = v&(8>>uint(j)) != 0
+		}
+	}
+
+	return newHash(bitList), nil
+}
+
+// 汉明距离，两个哈希位数不同时返回错误
+func (h Hash)Distance(other Hash) (int, error) {
+	if h.size != other.size {
+		return 0, errors.New("hashes have different sizes")
+	}
+
+	d := 0
+	for i := range h.words {
+		d += bits.OnesCount64(h.words[i] ^ other.words[i])
+	}
+
+	return d, nil
+}
+
+// 感知哈希算法的统一接口
+type Hasher interface {
+	Name() string
+	Hash(il *ImgLoader) Hash
+}
+
+var hasherNames = []string{"ahash", "dhash", "phash", "whash", "blockmean", "colormoment"}
+
+// 按名字创建哈希算法，size 为边长，哈希位数一般为 size * size，小于 2 时取 8
+func NewHasher(name string, size int) (Hasher, error) {
+	switch strings.ToLower(name) {
+	case "ahash":
+		return AverageHash{Size: size}, nil
+	case "dhash":
+		return DifferenceHash{Size: size}, nil
+	case "phash":
+		return PerceptualHash{Size: size}, nil
+	case "whash":
+		return WaveletHash{Size: size}, nil
+	case "blockmean":
+		return BlockMeanHash{Size: size}, nil
+	case "colormoment":
+		return ColorMomentHash{Size: size}, nil
+	}
+
+	return nil, fmt.Errorf("unknown hash algorithm: %s", name)
+}
+
+func HasherNames() []string {
+	return hasherNames
+}
+
+// 均值哈希：缩小到 Size x Size 的灰度图，像素是否大于均值
+type AverageHash struct {
+	Size int
+}
+
+func (h AverageHash)Name() string {
+	return "ahash"
+}
+
+func (h AverageHash)Hash(il *ImgLoader) Hash {
+	size := hashSize(h.Size)
+	gray := hashPlane(il, size, size)
+	mean := 0.0
+	for _, row := range gray {
+		for _, v := range row {
+			mean += v
+		}
+	}
+	mean /= float64(size * size)
+
+	return thresholdHash(gray, mean)
+}
+
+// 差值哈希：缩小到 Size x (Size+1) 的灰度图，每行相邻像素左边是否比右边亮
+type DifferenceHash struct {
+	Size int
+}
+
+func (h DifferenceHash)Name() string {
+	return "dhash"
+}
+
+func (h DifferenceHash)Hash(il *ImgLoader) Hash {
+	size := hashSize(h.Size)
+	gray := hashPlane(il, size, size+1)
+	bitList := make([]bool, 0, size*size)
+	for _, row := range gray {
+		for wi := 0; wi < size; wi++ {
+			bitList = append(bitList, row[wi] > row[wi+1])
+		}
+	}
+
+	return newHash(bitList)
+}
+
+// DCT 哈希：缩小到 4Size x 4Size 的灰度图做二维 DCT，
+// 取左上角 Size x Size 的低频系数，与除直流分量外的系数中位数比较
+type PerceptualHash struct {
+	Size int
+}
+
+func (h PerceptualHash)Name() string {
+	return "phash"
+}
+
+func (h PerceptualHash)Hash(il *ImgLoader) Hash {
+	size := hashSize(h.Size)
+	n := 4 * size
+	gray := hashPlane(il, n, n)
+
+	cosTable := newPlane(size, n)
+	for k := range cosTable {
+		for x := range cosTable[k] {
+			cosTable[k][x] = math.Cos(math.Pi * float64((2*x+1)*k) / float64(2*n))
+		}
+	}
+
+	// 先对每行做一维 DCT，只保留前 Size 个系数，再对列做
+	rows := newPlane(n, size)
+	for y := range rows {
+		for u := range rows[y] {
+			for x := 0; x < n; x++ {
+				rows[y][u] += gray[y][x] * cosTable[u][x]
+			}
+		}
+	}
+	coef := newPlane(size, size)
+	for v := range coef {
+		for u := range coef[v] {
+			for y := 0; y < n; y++ {
+				coef[v][u] += rows[y][u] * cosTable[v][y]
+			}
+		}
+	}
+
+	values := make([]float64, 0, size*size-1)
+	for v := range coef {
+		for u := range coef[v] {
+			if u != 0 || v != 0 {
+				values = append(values, coef[v][u])
+			}
+		}
+	}
+
+	return thresholdHash(coef, median(values))
+}
+
+// 小波哈希：缩小到 2 的幂次边长的灰度图，去掉直流分量后做多级 Daubechies-4 小波分解，
+// 取 Size x Size 的低频（LL）子带与其中位数比较。
+// 与 Haar 小波不同，db4 的低通滤波器跨越相邻的块，因此结果不等同于块均值
+type WaveletHash struct {
+	Size int
+}
+
+func (h WaveletHash)Name() string {
+	return "whash"
+}
+
+// db4 低通分解滤波器
+var db4 = []float64{0.48296291314469025, 0.83651630373746899, 0.22414386804185735, -0.12940952255092145}
+
+func (h WaveletHash)Hash(il *ImgLoader) Hash {
+	size := hashSize(h.Size)
+	// 边长取不小于 8Size 的 2 的幂
+	n := 1
+	for n < 8*size {
+		n *= 2
+	}
+	ll := hashPlane(il, n, n)
+
+	mean := 0.0
+	for _, row := range ll {
+		for _, v := range row {
+			mean += v
+		}
+	}
+	mean /= float64(n * n)
+	for _, row := range ll {
+		for x := range row {
+			row[x] -= mean
+		}
+	}
+
+	for len(ll) >= 2*size {
+		ll = waveletLL(ll)
+	}
+	if len(ll) != size {
+		// Size 不是 2 的幂时，把最后一级 LL 缩放到 Size x Size
+		ll = resamplePlane(ll, size, size)
+	}
+
+	values := make([]float64, 0, size*size)
+	for _, row := range ll {
+		values = append(values, row...)
+	}
+
+	return thresholdHash(ll, median(values))
+}
+
+// 一级 db4 分解的 LL 子带：先行后列做低通滤波并隔点采样，边界镜像
+func waveletLL(p plane) plane {
+	height, width := len(p), len(p[0])
+
+	tmp := newPlane(height, width/2)
+	for y := range tmp {
+		for x := range tmp[y] {
+			for k, c := range db4 {
+				tmp[y][x] += c * p[y][reflectIndex(2*x+k-1, width)]
+			}
+		}
+	}
+
+	dst := newPlane(height/2, width/2)
+	for y := range dst {
+		for x := range dst[y] {
+			for k, c := range db4 {
+				dst[y][x] += c * tmp[reflectIndex(2*y+k-1, height)][x]
+			}
+		}
+	}
+
+	return dst
+}
+
+// 块均值哈希：缩小到 16Size x 16Size 的灰度图，分成 Size x Size 个块，块均值是否不小于所有块均值的中位数
+type BlockMeanHash struct {
+	Size int
+}
+
+func (h BlockMeanHash)Name() string {
+	return "blockmean"
+}
+
+func (h BlockMeanHash)Hash(il *ImgLoader) Hash {
+	size := hashSize(h.Size)
+	const block = 16
+	gray := hashPlane(il, size*block, size*block)
+
+	means := newPlane(size, size)
+	values := make([]float64, 0, size*size)
+	for by := range means {
+		for bx := range means[by] {
+			sum := 0.0
+			for y := by * block; y < (by+1)*block; y++ {
+				for x := bx * block; x < (bx+1)*block; x++ {
+					sum += gray[y][x]
+				}
+			}
+			means[by][bx] = sum / block / block
+			values = append(values, means[by][bx])
+		}
+	}
+
+	m := median(values)
+	bitList := make([]bool, 0, size*size)
+	for _, row := range means {
+		for _, v := range row {
+			bitList = append(bitList, v >= m)
+		}
+	}
+
+	return newHash(bitList)
+}
+
+// 颜色矩哈希：把图片分成 Size x Size 个块，计算每块在 Y、Cb、Cr 通道上的一阶矩（均值）与二阶矩（标准差），
+// 分别与所有块对应矩的中位数比较，共 6 * Size * Size 位。对颜色变化敏感，适合区分构图相同但配色不同的图片
+type ColorMomentHash struct {
+	Size int
+}
+
+func (h ColorMomentHash)Name() string {
+	return "colormoment"
+}
+
+func (h ColorMomentHash)Hash(il *ImgLoader) Hash {
+	size := hashSize(h.Size)
+	const block = 8
+	n := size * block
+	matrix := new(ImgProcessor).ResizeWith(il, n, n, Box).GetMatrix()
+
+	// moments[i][b] 为第 b 块的第 i 个矩，依次为 Y、Cb、Cr 的均值与标准差
+	var moments [6][]float64
+	for by := 0; by < size; by++ {
+		for bx := 0; bx < size; bx++ {
+			var sum, sqSum [3]float64
+			for y := by * block; y < (by+1)*block; y++ {
+				for x := bx * block; x < (bx+1)*block; x++ {
+					p := matrix[y][x]
+					yy, cb, cr := RGBToYCbCr(float64(p[0])/255, float64(p[1])/255, float64(p[2])/255)
+					for c, v := range []float64{yy, cb, cr} {
+						sum[c] += v
+						sqSum[c] += v * v
+					}
+				}
+			}
+			for c := 0; c < 3; c++ {
+				mean := sum[c] / block / block
+				moments[2*c] = append(moments[2*c], mean)
+				moments[2*c+1] = append(moments[2*c+1], math.Sqrt(math.Max(0, sqSum[c]/block/block-mean*mean)))
+			}
+		}
+	}
+
+	bitList := make([]bool, 0, 6*size*size)
+	for _, values := range moments {
+		m := median(values)
+		for _, v := range values {
+			bitList = append(bitList, v > m)
+		}
+	}
+
+	return newHash(bitList)
+}
+
+// 边长小于 2 时取 8
+func hashSize(size int) int {
+	if size < 2 {
+		return 8
+	}
+
+	return size
+}
+
+// 以盒式滤波缩小后的灰度平面
+func hashPlane(il *ImgLoader, height, width int) plane {
+	return grayPlane(new(ImgProcessor).ResizeWith(il, height, width, Box).GetMatrix())
+}
+
+// 按双线性插值缩放浮点平面
+func resamplePlane(p plane, height, width int) plane {
+	srcHeight, srcWidth := len(p), len(p[0])
+	dst := newPlane(height, width)
+	for y := range dst {
+		sy := math.Max(0, (float64(y)+0.5)*float64(srcHeight)/float64(height)-0.5)
+		y0 := int(sy)
+		y1 := clampInt(y0+1, 0, srcHeight-1)
+		fy := sy - float64(y0)
+		for x := range dst[y] {
+			sx := math.Max(0, (float64(x)+0.5)*float64(srcWidth)/float64(width)-0.5)
+			x0 := int(sx)
+			x1 := clampInt(x0+1, 0, srcWidth-1)
+			fx := sx - float64(x0)
+			top := p[y0][x0]*(1-fx) + p[y0][x1]*fx
+			bottom := p[y1][x0]*(1-fx) + p[y1][x1]*fx
+			dst[y][x] = top*(1-fy) + bottom*fy
+		}
+	}
+
+	return dst
+}
+
+// 平面中大于 t 的位置为 1
+func thresholdHash(p plane, t float64) Hash {
+	bitList := make([]bool, 0, len(p)*len(p[0]))
+	for _, row := range p {
+		for _, v := range row {
+			bitList = append(bitList, v > t)
+		}
+	}
+
+	return newHash(bitList)
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
@@ -0,0 +1,199 @@
+package tool
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// 带渐变、色块与圆形的合成图片，作为哈希的测试样本
+func hashFixture(width, height int) *ImgLoader {
+	matrix := NewRGBAMatrix(height, width)
+	for hi := range matrix {
+		for wi := range matrix[hi] {
+			x, y := float64(wi)/float64(width), float64(hi)/float64(height)
+			r, g, b := 255*x, 255*y, 128+100*math.Sin(6*x+3*y)
+			if x > 0.55 && x < 0.85 && y > 0.15 && y < 0.45 {
+				r, g, b = 30, 200, 40
+			}
+			if (x-0.3)*(x-0.3)+(y-0.7)*(y-0.7) < 0.04 {
+				r, g, b = 240, 240, 20
+			}
+			matrix[hi][wi][0], matrix[hi][wi][1], matrix[hi][wi][2], matrix[hi][wi][3] = clampUint8(r), clampUint8(g), clampUint8(b), 255
+		}
+	}
+
+	return &ImgLoader{filename: "fixture", matrix: matrix}
+}
+
+func randomHash(rnd *rand.Rand, size int) Hash {
+	bitList := make([]bool, size)
+	for i := range bitList {
+		bitList[i] = rnd.Intn(2) == 1
+	}
+
+	return newHash(bitList)
+}
+
+func TestHashHexRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, size := range []int{1, 5, 7, 30, 63, 64, 65, 81, 130, 256} {
+		for i := 0; i < 20; i++ {
+			h := randomHash(rnd, size)
+			hex := h.Hex()
+			if len(hex) != (size+3)/4 {
+				t.Fatalf("size %d: hex %q has %d digits", size, hex, len(hex))
+			}
+
+			parsed, err := ParseHash(hex, size)
+			if err != nil {
+				t.Fatalf("size %d: %s", size, err.Error())
+			}
+			if d, err := h.Distance(parsed); err != nil || d != 0 {
+				t.Fatalf("size %d: %s -> %s (distance %d, err %v)", size, hex, parsed.Hex(), d, err)
+			}
+			for j := 0; j < size; j++ {
+				if h.Bit(j) != parsed.Bit(j) {
+					t.Fatalf("size %d: bit %d differs after round-trip", size, j)
+				}
+			}
+		}
+	}
+}
+
+func TestParseHashErrors(t *testing.T) {
+	cases := []struct {
+		s    string
+		size int
+	}{
+		{"abc", 64},
+		{"0123456789abcdeg", 64},
+		{"ff", 9},
+		{"f", 5},
+	}
+	for _, c := range cases {
+		if _, err := ParseHash(c.s, c.size); err == nil {
+			t.Errorf("ParseHash(%q, %d): expected an error", c.s, c.size)
+		}
+	}
+
+	h, err := ParseHash("F0", 0)
+	if err != nil || h.Len() != 8 || h.Uint64() != 0xf0 {
+		t.Errorf("ParseHash(\"F0\", 0) = %v (%d bits), %v", h, h.Len(), err)
+	}
+}
+
+func TestHashUint64(t *testing.T) {
+	cases := []struct {
+		bits string
+		want uint64
+	}{
+		{"", 0},
+		{"1", 1},
+		{"10", 2},
+		{"1011", 11},
+		{"00000001", 1},
+		{"111111111", 511},
+	}
+	for _, c := range cases {
+		bitList := make([]bool, len(c.bits))
+		for i, b := range c.bits {
+			bitList[i] = b == '1'
+		}
+		if got := newHash(bitList).Uint64(); got != c.want {
+			t.Errorf("Uint64(%q) = %d, want %d", c.bits, got, c.want)
+		}
+	}
+
+	rnd := rand.New(rand.NewSource(2))
+	for i := 0; i < 100; i++ {
+		v := rnd.Uint64()
+		bitList := make([]bool, 64)
+		for j := range bitList {
+			bitList[j] = v&(1<<uint(63-j)) != 0
+		}
+		if got := newHash(bitList).Uint64(); got != v {
+			t.Fatalf("Uint64 of 64 bits = %x, want %x", got, v)
+		}
+	}
+}
+
+func TestHashDistance(t *testing.T) {
+	a, _ := ParseHash("ff00", 16)
+	b, _ := ParseHash("0f0f", 16)
+	if d, err := a.Distance(b); err != nil || d != 8 {
+		t.Errorf("Distance = %d, %v, want 8", d, err)
+	}
+
+	c, _ := ParseHash("ff0", 12)
+	if _, err := a.Distance(c); err == nil {
+		t.Error("expected an error for hashes of different sizes")
+	}
+}
+
+func TestHashers(t *testing.T) {
+	il := hashFixture(160, 120)
+	same := hashFixture(160, 120)
+	small := new(ImgProcessor).ResizeWith(il, 60, 80, Box)
+	// 旋转 180 度后色块与渐变的位置都变了，作为不相关的图片
+	unrelated := new(ImgProcessor).Rotate180(il)
+
+	for _, name := range HasherNames() {
+		for _, size := range []int{8, 16} {
+			hasher, err := NewHasher(name, size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := hasher.Hash(il)
+			if h.Len() == 0 {
+				t.Fatalf("%s/%d: empty hash", name, size)
+			}
+
+			if d, err := h.Distance(hasher.Hash(same)); err != nil || d != 0 {
+				t.Errorf("%s/%d: identical image has distance %d, %v", name, size, d, err)
+			}
+
+			// 缩小后的图片应当非常接近，允许不超过 1/8 的位不同
+			d, err := h.Distance(hasher.Hash(small))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d > h.Len()/8 {
+				t.Errorf("%s/%d: resized copy has distance %d of %d bits", name, size, d, h.Len())
+			}
+
+			// 返回常量的哈希也能通过上面两项检查，不相关的图片至少要有 1/4 的位不同
+			if d, _ = h.Distance(hasher.Hash(unrelated)); d <= h.Len()/4 {
+				t.Errorf("%s/%d: unrelated image has distance %d of %d bits", name, size, d, h.Len())
+			}
+		}
+	}
+}
+
+// 固定 DCT 与 db4 小波实现的输出
+func TestHashersGolden(t *testing.T) {
+	il := hashFixture(160, 120)
+	cases := []struct {
+		name string
+		want string
+	}{
+		{"phash", "a23b1dc42f38d8c7"},
+		{"whash", "00000607177fffff"},
+	}
+
+	for _, c := range cases {
+		hasher, err := NewHasher(c.name, 8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hasher.Hash(il).Hex(); got != c.want {
+			t.Errorf("%s = %s, want %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestNewHasherUnknown(t *testing.T) {
+	if _, err := NewHasher("nohash", 8); err == nil {
+		t.Error("expected an error for an unknown algorithm")
+	}
+}
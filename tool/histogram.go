@@ -0,0 +1,299 @@
+package tool
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+)
+
+// 各通道及亮度的直方图，下标为取值，值为像素个数
+type Histogram struct {
+	R    [256]int
+	G    [256]int
+	B    [256]int
+	A    [256]int
+	Luma [256]int
+}
+
+// 像素总数
+func (h *Histogram)Total() int {
+	var total int
+	for _, n := range h.Luma {
+		total += n
+	}
+
+	return total
+}
+
+func (ip *ImgProcessor)GetHistogram(il *ImgLoader) (hist Histogram) {
+	matrix := il.GetMatrix()
+	gray := grayPlane(matrix)
+
+	for hi := range matrix {
+		for wi := range matrix[hi] {
+			hist.R[matrix[hi][wi][0]]++
+			hist.G[matrix[hi][wi][1]]++
+			hist.B[matrix[hi][wi][2]]++
+			hist.A[matrix[hi][wi][3]]++
+			hist.Luma[clampUint8(gray[hi][wi])]++
+		}
+	}
+
+	return
+}
+
+// 将直方图绘制为折线图，灰色填充部分为亮度，红绿蓝折线为各通道
+func SaveHistogramPng(filename string, hist Histogram) (err error) {
+	const height, binWidth = 300, 2
+	width := 256 * binWidth
+
+	var peak int
+	for i := 0; i < 256; i++ {
+		for _, n := range []int{hist.R[i], hist.G[i], hist.B[i], hist.Luma[i]} {
+			if n > peak {
+				peak = n
+			}
+		}
+	}
+	if peak == 0 {
+		return errors.New("empty histogram")
+	}
+
+	chart := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := range chart.Pix {
+		chart.Pix[i] = math.MaxUint8
+	}
+
+	toY := func(n int) int {
+		return height - 1 - int(float64(n)/float64(peak)*float64(height-1))
+	}
+
+	for i := 0; i < 256; i++ {
+		for x := i * binWidth; x < (i+1)*binWidth; x++ {
+			for y := toY(hist.Luma[i]); y < height; y++ {
+				chart.SetNRGBA(x, y, color.NRGBA{R: 200, G: 200, B: 200, A: 255})
+			}
+		}
+	}
+
+	curves := []struct {
+		bins *[256]int
+		c    color.NRGBA
+	}{
+		{&hist.R, color.NRGBA{R: 220, A: 255}},
+		{&hist.G, color.NRGBA{G: 180, A: 255}},
+		{&hist.B, color.NRGBA{B: 220, A: 255}},
+	}
+	for _, curve := range curves {
+		for i := 0; i < 255; i++ {
+			y0, y1 := toY(curve.bins[i]), toY(curve.bins[i+1])
+			if y0 > y1 {
+				y0, y1 = y1, y0
+			}
+			for x := i * binWidth; x < (i+1)*binWidth; x++ {
+				for y := y0; y <= y1; y++ {
+					chart.SetNRGBA(x, y, curve.c)
+				}
+			}
+		}
+	}
+
+	outfile, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer outfile.Close()
+
+	return png.Encode(outfile, chart)
+}
+
+// 全局直方图均衡化，只作用于 YCbCr 的亮度分量，色度保持不变
+func (ip *ImgProcessor)EqualizeHist(il *ImgLoader) *ImgLoader {
+	src := il.GetMatrix()
+
+	var hist [256]int
+	for hi := range src {
+		for wi := range src[hi] {
+			y, _, _ := color.RGBToYCbCr(src[hi][wi][0], src[hi][wi][1], src[hi][wi][2])
+			hist[y]++
+		}
+	}
+	lut := equalizeLUT(hist)
+
+	imgMatrix := NewRGBAMatrix(il.GetMY(), il.GetMX())
+	for hi := range imgMatrix {
+		for wi := range imgMatrix[hi] {
+			y, cb, cr := color.RGBToYCbCr(src[hi][wi][0], src[hi][wi][1], src[hi][wi][2])
+			r, g, b := color.YCbCrToRGB(lut[y], cb, cr)
+			imgMatrix[hi][wi][0], imgMatrix[hi][wi][1], imgMatrix[hi][wi][2] = r, g, b
+			imgMatrix[hi][wi][3] = src[hi][wi][3]
+		}
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: imgMatrix,
+		img: il.GetImg(),
+	}
+}
+
+// 直方图规定化：逐通道调整 il 的分布，使其累计分布接近 ref
+func (ip *ImgProcessor)MatchHistogram(il *ImgLoader, ref *ImgLoader) *ImgLoader {
+	srcHist, refHist := ip.GetHistogram(il), ip.GetHistogram(ref)
+	luts := [3][256]uint8{
+		matchLUT(srcHist.R, refHist.R),
+		matchLUT(srcHist.G, refHist.G),
+		matchLUT(srcHist.B, refHist.B),
+	}
+
+	return applyLUT(il, luts)
+}
+
+// 限制对比度的自适应直方图均衡化，作用于亮度分量。
+// tileSize 为每个分块的边长（像素），clipLimit 为相对于平均每级像素数的裁剪倍数，常用 2 ~ 4，
+// clipLimit 不大于 0 时不裁剪，退化为普通的 AHE
+func (ip *ImgProcessor)CLAHE(il *ImgLoader, tileSize int, clipLimit float64) *ImgLoader {
+	src := il.GetMatrix()
+	height, width := il.GetMY(), il.GetMX()
+	if tileSize < 8 {
+		tileSize = 8
+	}
+	tilesY := (height + tileSize - 1) / tileSize
+	tilesX := (width + tileSize - 1) / tileSize
+
+	luma := make([][]uint8, height)
+	for hi := range luma {
+		luma[hi] = make([]uint8, width)
+		for wi := range luma[hi] {
+			luma[hi][wi], _, _ = color.RGBToYCbCr(src[hi][wi][0], src[hi][wi][1], src[hi][wi][2])
+		}
+	}
+
+	luts := make([][][256]uint8, tilesY)
+	for ty := range luts {
+		luts[ty] = make([][256]uint8, tilesX)
+		for tx := range luts[ty] {
+			var hist [256]int
+			y1, x1 := clampInt((ty+1)*tileSize, 0, height), clampInt((tx+1)*tileSize, 0, width)
+			for hi := ty * tileSize; hi < y1; hi++ {
+				for wi := tx * tileSize; wi < x1; wi++ {
+					hist[luma[hi][wi]]++
+				}
+			}
+			if clipLimit > 0 {
+				n := (y1 - ty*tileSize) * (x1 - tx*tileSize)
+				clipHistogram(&hist, int(math.Max(1, clipLimit*float64(n)/256)))
+			}
+			luts[ty][tx] = equalizeLUT(hist)
+		}
+	}
+
+	imgMatrix := NewRGBAMatrix(height, width)
+	for hi := range imgMatrix {
+		// 以分块中心为插值节点
+		fy := (float64(hi)+0.5)/float64(tileSize) - 0.5
+		ty0 := clampInt(int(math.Floor(fy)), 0, tilesY-1)
+		ty1 := clampInt(ty0+1, 0, tilesY-1)
+		wy := math.Min(math.Max(fy-float64(ty0), 0), 1)
+
+		for wi := range imgMatrix[hi] {
+			fx := (float64(wi)+0.5)/float64(tileSize) - 0.5
+			tx0 := clampInt(int(math.Floor(fx)), 0, tilesX-1)
+			tx1 := clampInt(tx0+1, 0, tilesX-1)
+			wx := math.Min(math.Max(fx-float64(tx0), 0), 1)
+
+			y := luma[hi][wi]
+			top := (1-wx)*float64(luts[ty0][tx0][y]) + wx*float64(luts[ty0][tx1][y])
+			bottom := (1-wx)*float64(luts[ty1][tx0][y]) + wx*float64(luts[ty1][tx1][y])
+
+			_, cb, cr := color.RGBToYCbCr(src[hi][wi][0], src[hi][wi][1], src[hi][wi][2])
+			r, g, b := color.YCbCrToRGB(clampUint8((1-wy)*top+wy*bottom), cb, cr)
+			imgMatrix[hi][wi][0], imgMatrix[hi][wi][1], imgMatrix[hi][wi][2] = r, g, b
+			imgMatrix[hi][wi][3] = src[hi][wi][3]
+		}
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: imgMatrix,
+		img: il.GetImg(),
+	}
+}
+
+// 累计分布函数，归一化到 [0, 1]
+func cdf(hist [256]int) (c [256]float64) {
+	var total, sum int
+	for _, n := range hist {
+		total += n
+	}
+	if total == 0 {
+		return
+	}
+
+	for i, n := range hist {
+		sum += n
+		c[i] = float64(sum) / float64(total)
+	}
+
+	return
+}
+
+func equalizeLUT(hist [256]int) (lut [256]uint8) {
+	c := cdf(hist)
+
+	// 以最小的非零累计值为起点，避免整体偏亮
+	var cMin float64
+	for i := range c {
+		if hist[i] != 0 {
+			cMin = c[i]
+			break
+		}
+	}
+
+	for i := range lut {
+		if cMin >= 1 {
+			lut[i] = uint8(i)
+			continue
+		}
+		lut[i] = clampUint8((c[i] - cMin) / (1 - cMin) * 255)
+	}
+
+	return
+}
+
+func matchLUT(src, ref [256]int) (lut [256]uint8) {
+	srcCDF, refCDF := cdf(src), cdf(ref)
+
+	j := 0
+	for i := range lut {
+		for j < 255 && refCDF[j] < srcCDF[i] {
+			j++
+		}
+		lut[i] = uint8(j)
+	}
+
+	return
+}
+
+// 将超过 limit 的部分均匀分配回所有灰度级
+func clipHistogram(hist *[256]int, limit int) {
+	var excess int
+	for i := range hist {
+		if hist[i] > limit {
+			excess += hist[i] - limit
+			hist[i] = limit
+		}
+	}
+
+	for i := range hist {
+		hist[i] += excess / 256
+	}
+	for i := 0; i < excess%256; i++ {
+		hist[i*256/(excess%256)]++
+	}
+}
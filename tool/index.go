@@ -0,0 +1,411 @@
+package tool
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 图片指纹索引，以 JSON 格式持久化到本地文件，查询时在内存中建立 BK 树
+type ImageIndex struct {
+	Algorithm string                 `json:"algorithm"`
+	HashSize  int                    `json:"hash_size"`
+	Bits      int                    `json:"bits"`
+	Entries   map[string]*IndexEntry `json:"entries"` // 以文件路径为键
+
+	hasher Hasher
+	tree   *bkNode
+}
+
+type IndexEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`            // Unix 纳秒，与 Size 一起用于判断文件是否变化
+	Hash    string `json:"hash"`             // 十六进制
+	Failed  bool   `json:"failed,omitempty"` // 无法解码，文件改变前不再重试
+
+	hash Hash
+}
+
+// 查询结果
+type IndexMatch struct {
+	Path     string `json:"path"`
+	Distance int    `json:"distance"`
+}
+
+// 一组互为近似重复的图片，Images[0] 为代表，其余按与代表的距离排序
+type DuplicateGroup struct {
+	Images []IndexMatch `json:"images"`
+}
+
+func NewImageIndex(algorithm string, hashSize int) (*ImageIndex, error) {
+	hasher, err := NewHasher(algorithm, hashSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ImageIndex{
+		Algorithm: hasher.Name(),
+		HashSize: hashSize,
+		Entries: make(map[string]*IndexEntry),
+		hasher: hasher,
+	}, nil
+}
+
+func LoadImageIndex(filename string) (*ImageIndex, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &ImageIndex{}
+	if err = json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+	if idx.hasher, err = NewHasher(idx.Algorithm, idx.HashSize); err != nil {
+		return nil, err
+	}
+	if idx.Entries == nil {
+		idx.Entries = make(map[string]*IndexEntry)
+	}
+	for path, entry := range idx.Entries {
+		if entry.Failed {
+			continue
+		}
+		if entry.hash, err = ParseHash(entry.Hash, idx.Bits); err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err.Error())
+		}
+	}
+
+	return idx, nil
+}
+
+// 先写临时文件再改名，避免中途出错损坏已有索引
+func (idx *ImageIndex)Save(filename string) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+
+	tmp := filename + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0666); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filename)
+}
+
+// Update 的统计结果
+type UpdateStats struct {
+	Added   int `json:"added"`
+	Updated int `json:"updated"`
+	Removed int `json:"removed"`
+	Skipped int `json:"skipped"` // 无法读取而跳过的文件或目录
+	Failed  int `json:"failed"`  // 本次无法解码的图片
+}
+
+// 遍历 root 目录树，为新增或修改过的 jpg、png 图片计算指纹，并删除 root 下已不存在的条目。
+// 无法读取的文件或目录被跳过并计数，其下已有的条目保持不变。
+// 无法解码的图片记为失败条目，大小与修改时间不变时不再重试，也不参与查询
+func (idx *ImageIndex)Update(root string) (stats UpdateStats, err error) {
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return
+	}
+
+	type job struct {
+		path  string
+		info  os.FileInfo
+		isNew bool
+	}
+	var jobs []job
+	seen := make(map[string]bool)
+	var skippedDirs []string
+
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// root 本身无法读取时放弃，其余的错误只跳过出错的文件或目录
+			if path == root {
+				return err
+			}
+			stats.Skipped++
+			if info != nil && info.IsDir() {
+				skippedDirs = append(skippedDirs, path)
+				return filepath.SkipDir
+			}
+			seen[path] = true
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if info.IsDir() || (ext != ".jpg" && ext != ".jpeg" && ext != ".png") {
+			return nil
+		}
+
+		seen[path] = true
+		entry, ok := idx.Entries[path]
+		if ok && entry.Size == info.Size() && entry.ModTime == info.ModTime().UnixNano() {
+			return nil
+		}
+		jobs = append(jobs, job{path: path, info: info, isNew: !ok})
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	for path := range idx.Entries {
+		if seen[path] || !underRoot(path, root) {
+			continue
+		}
+		inSkipped := false
+		for _, dir := range skippedDirs {
+			if underRoot(path, dir) {
+				inSkipped = true
+				break
+			}
+		}
+		if !inSkipped {
+			delete(idx.Entries, path)
+			stats.Removed++
+		}
+	}
+
+	// 解码与计算指纹较慢，按 CPU 数并行
+	hashes := make([]*Hash, len(jobs))
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				il, err := NewImgLoader(jobs[i].path)
+				if err != nil {
+					continue
+				}
+				h := idx.hasher.Hash(&il)
+				hashes[i] = &h
+			}
+		}()
+	}
+	for i := range jobs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	for i, j := range jobs {
+		if hashes[i] == nil {
+			idx.Entries[j.path] = &IndexEntry{
+				Size: j.info.Size(),
+				ModTime: j.info.ModTime().UnixNano(),
+				Failed: true,
+			}
+			stats.Failed++
+			continue
+		}
+
+		idx.Bits = hashes[i].Len()
+		idx.Entries[j.path] = &IndexEntry{
+			Size: j.info.Size(),
+			ModTime: j.info.ModTime().UnixNano(),
+			Hash: hashes[i].Hex(),
+			hash: *hashes[i],
+		}
+		if j.isNew {
+			stats.Added++
+		} else {
+			stats.Updated++
+		}
+	}
+	idx.tree = nil
+
+	return
+}
+
+// 与 il 的指纹距离不超过 maxDistance 的所有图片，按距离排序
+func (idx *ImageIndex)QueryImage(il *ImgLoader, maxDistance int) []IndexMatch {
+	return idx.Query(idx.hasher.Hash(il), maxDistance)
+}
+
+func (idx *ImageIndex)Query(h Hash, maxDistance int) []IndexMatch {
+	var matches []IndexMatch
+	if h.Len() != idx.Bits {
+		return matches
+	}
+
+	idx.buildTree()
+	idx.tree.search(h, maxDistance, func(path string, d int) {
+		matches = append(matches, IndexMatch{Path: path, Distance: d})
+	})
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].Path < matches[j].Path
+	})
+
+	return matches
+}
+
+// 把 root 目录树下距离不超过 maxDistance 的图片传递地归为一组，只返回包含两张及以上图片的组。
+// root 为空时包含索引中的所有图片
+func (idx *ImageIndex)Duplicates(root string, maxDistance int) []DuplicateGroup {
+	paths := idx.Paths(root)
+	inRoot := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		inRoot[p] = true
+	}
+
+	// 并查集
+	parent := make(map[string]string, len(paths))
+	var find func(p string) string
+	find = func(p string) string {
+		if parent[p] != p {
+			parent[p] = find(parent[p])
+		}
+		return parent[p]
+	}
+	for _, p := range paths {
+		parent[p] = p
+	}
+
+	idx.buildTree()
+	for _, p := range paths {
+		idx.tree.search(idx.Entries[p].hash, maxDistance, func(q string, d int) {
+			if !inRoot[q] {
+				return
+			}
+			rp, rq := find(p), find(q)
+			if rp < rq {
+				parent[rq] = rp
+			} else if rq < rp {
+				parent[rp] = rq
+			}
+		})
+	}
+
+	members := make(map[string][]string)
+	for _, p := range paths {
+		root := find(p)
+		members[root] = append(members[root], p)
+	}
+
+	var groups []DuplicateGroup
+	for _, p := range paths {
+		list := members[p]
+		if len(list) < 2 {
+			continue
+		}
+
+		rep := idx.Entries[list[0]].hash
+		group := DuplicateGroup{}
+		for _, q := range list {
+			d, _ := rep.Distance(idx.Entries[q].hash)
+			group.Images = append(group.Images, IndexMatch{Path: q, Distance: d})
+		}
+		sort.SliceStable(group.Images[1:], func(i, j int) bool {
+			return group.Images[i+1].Distance < group.Images[j+1].Distance
+		})
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// root 目录树下已索引的图片路径（不含失败条目），按字典序排列，root 为空时返回全部
+func (idx *ImageIndex)Paths(root string) []string {
+	if root != "" {
+		if abs, err := filepath.Abs(root); err == nil {
+			root = abs
+		}
+	}
+
+	paths := make([]string, 0, len(idx.Entries))
+	for path := range idx.Entries {
+		if !idx.Entries[path].Failed && (root == "" || underRoot(path, root)) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	return paths
+}
+
+func underRoot(path, root string) bool {
+	return strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
+
+func (idx *ImageIndex)buildTree() {
+	if idx.tree != nil {
+		return
+	}
+
+	// 按路径顺序插入，使树的形状与结果可复现
+	for _, path := range idx.Paths("") {
+		h := idx.Entries[path].hash
+		if idx.tree == nil {
+			idx.tree = &bkNode{hash: h}
+		}
+		idx.tree.insert(h, path)
+	}
+	if idx.tree == nil {
+		idx.tree = &bkNode{hash: newHash(make([]bool, idx.Bits))}
+	}
+}
+
+// BK 树节点，指纹完全相同的图片放在同一节点
+type bkNode struct {
+	hash     Hash
+	paths    []string
+	children map[int]*bkNode
+}
+
+func (n *bkNode)insert(h Hash, path string) {
+	for {
+		d, _ := n.hash.Distance(h)
+		if d == 0 {
+			n.paths = append(n.paths, path)
+			return
+		}
+
+		child, ok := n.children[d]
+		if !ok {
+			if n.children == nil {
+				n.children = make(map[int]*bkNode)
+			}
+			n.children[d] = &bkNode{hash: h, paths: []string{path}}
+			return
+		}
+		n = child
+	}
+}
+
+// 由三角不等式，只有与节点距离在 [d-max, d+max] 之间的子树可能包含结果
+func (n *bkNode)search(h Hash, maxDistance int, visit func(path string, d int)) {
+	stack := []*bkNode{n}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		d, err := node.hash.Distance(h)
+		if err != nil {
+			return
+		}
+		if d <= maxDistance {
+			for _, path := range node.paths {
+				visit(path, d)
+			}
+		}
+		for cd, child := range node.children {
+			if cd >= d-maxDistance && cd <= d+maxDistance {
+				stack = append(stack, child)
+			}
+		}
+	}
+}
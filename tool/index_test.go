@@ -0,0 +1,340 @@
+package tool
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 统一为绝对路径，与索引中的键一致
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+// 以 seed 生成互不相似的测试图片
+func writeFixture(t *testing.T, filename string, seed int64) {
+	rnd := rand.New(rand.NewSource(seed))
+	matrix := NewRGBAMatrix(32, 32)
+	for hi := range matrix {
+		for wi := range matrix[hi] {
+			v := uint8(rnd.Intn(256))
+			matrix[hi][wi][0], matrix[hi][wi][1], matrix[hi][wi][2], matrix[hi][wi][3] = v, v, v, 255
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveAsPng(filename, matrix); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func indexWithHashes(t *testing.T, hashes map[string]Hash) *ImageIndex {
+	idx, err := NewImageIndex("dhash", 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, h := range hashes {
+		idx.Bits = h.Len()
+		idx.Entries[path] = &IndexEntry{Hash: h.Hex(), hash: h}
+	}
+
+	return idx
+}
+
+func TestBKTreeMatchesLinearScan(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	hashes := make(map[string]Hash)
+	var base []Hash
+	for i := 0; i < 300; i++ {
+		// 一部分哈希由已有哈希翻转少量位得到，使查询结果不为空
+		h := randomHash(rnd, 64)
+		if len(base) > 0 && rnd.Intn(2) == 0 {
+			h = flipBits(rnd, base[rnd.Intn(len(base))], rnd.Intn(8))
+		}
+		base = append(base, h)
+		hashes[filepath.Join("/img", string(rune('a'+i%26)), string(rune('a'+i/26))+".png")] = h
+	}
+	idx := indexWithHashes(t, hashes)
+
+	for q := 0; q < 50; q++ {
+		query := flipBits(rnd, base[rnd.Intn(len(base))], rnd.Intn(6))
+		for _, maxDistance := range []int{0, 3, 8, 20} {
+			var want []IndexMatch
+			for path, h := range hashes {
+				if d, _ := query.Distance(h); d <= maxDistance {
+					want = append(want, IndexMatch{Path: path, Distance: d})
+				}
+			}
+			sort.Slice(want, func(i, j int) bool {
+				if want[i].Distance != want[j].Distance {
+					return want[i].Distance < want[j].Distance
+				}
+				return want[i].Path < want[j].Path
+			})
+
+			got := idx.Query(query, maxDistance)
+			if len(got) != len(want) {
+				t.Fatalf("max %d: got %d matches, want %d", maxDistance, len(got), len(want))
+			}
+			for i := range got {
+				if got[i] != want[i] {
+					t.Fatalf("max %d: match %d = %v, want %v", maxDistance, i, got[i], want[i])
+				}
+			}
+		}
+	}
+}
+
+func flipBits(rnd *rand.Rand, h Hash, n int) Hash {
+	bitList := make([]bool, h.Len())
+	for i := range bitList {
+		bitList[i] = h.Bit(i)
+	}
+	for _, i := range rnd.Perm(len(bitList))[:n] {
+		bitList[i] = !bitList[i]
+	}
+
+	return newHash(bitList)
+}
+
+func TestImageIndexSaveLoad(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	rnd := rand.New(rand.NewSource(2))
+	// 30 位不是 4 的倍数，十六进制表示带有补齐位
+	hashes := map[string]Hash{
+		"/img/a.png": randomHash(rnd, 30),
+		"/img/b.png": randomHash(rnd, 30),
+		"/img/c.png": randomHash(rnd, 30),
+	}
+	idx := indexWithHashes(t, hashes)
+	idx.Entries["/img/a.png"].Size, idx.Entries["/img/a.png"].ModTime = 1234, 5678
+
+	filename := filepath.Join(dir, "test.index")
+	if err := idx.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadImageIndex(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Algorithm != "dhash" || loaded.HashSize != 8 || loaded.Bits != 30 || len(loaded.Entries) != 3 {
+		t.Fatalf("loaded %s/%d with %d bits and %d entries", loaded.Algorithm, loaded.HashSize, loaded.Bits, len(loaded.Entries))
+	}
+	for path, h := range hashes {
+		entry := loaded.Entries[path]
+		if d, err := entry.hash.Distance(h); err != nil || d != 0 {
+			t.Errorf("%s: hash %s, want %s", path, entry.hash.Hex(), h.Hex())
+		}
+	}
+	if e := loaded.Entries["/img/a.png"]; e.Size != 1234 || e.ModTime != 5678 {
+		t.Errorf("size/mtime = %d/%d, want 1234/5678", e.Size, e.ModTime)
+	}
+	if _, err = os.Stat(filename + ".tmp"); !os.IsNotExist(err) {
+		t.Error("temporary file left behind")
+	}
+}
+
+func TestImageIndexUpdatePrunes(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	for i, name := range []string{"a.png", "b.png", "sub/c.png"} {
+		writeFixture(t, filepath.Join(dir, name), int64(i))
+	}
+	// 其他目录下的条目不应被删除
+	other := indexWithHashes(t, map[string]Hash{"/elsewhere/x.png": randomHash(rand.New(rand.NewSource(3)), 64)})
+
+	idx, _ := NewImageIndex("dhash", 8)
+	idx.Bits = 64
+	idx.Entries["/elsewhere/x.png"] = other.Entries["/elsewhere/x.png"]
+
+	stats, err := idx.Update(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Added != 3 || stats.Removed != 0 || len(idx.Entries) != 4 {
+		t.Fatalf("first update: %+v, %d entries", stats, len(idx.Entries))
+	}
+
+	// 未改动时不重新计算
+	if stats, _ = idx.Update(dir); stats != (UpdateStats{}) {
+		t.Fatalf("second update: %+v", stats)
+	}
+
+	if err = os.Remove(filepath.Join(dir, "sub/c.png")); err != nil {
+		t.Fatal(err)
+	}
+	if stats, err = idx.Update(dir); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Removed != 1 || len(idx.Entries) != 3 {
+		t.Fatalf("after deletion: %+v, %d entries", stats, len(idx.Entries))
+	}
+	if _, ok := idx.Entries[filepath.Join(dir, "sub/c.png")]; ok {
+		t.Error("deleted file is still indexed")
+	}
+	if _, ok := idx.Entries["/elsewhere/x.png"]; !ok {
+		t.Error("entry outside the scanned root was removed")
+	}
+}
+
+func TestImageIndexUpdateSkipsUnreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	writeFixture(t, filepath.Join(dir, "a.png"), 1)
+	writeFixture(t, filepath.Join(dir, "locked/b.png"), 2)
+
+	idx, _ := NewImageIndex("dhash", 8)
+	if _, err := idx.Update(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	locked := filepath.Join(dir, "locked")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(locked, 0777)
+	writeFixture(t, filepath.Join(dir, "c.png"), 3)
+
+	stats, err := idx.Update(dir)
+	if err != nil {
+		t.Fatalf("unreadable directory aborted the update: %s", err.Error())
+	}
+	if stats.Skipped != 1 || stats.Added != 1 || stats.Removed != 0 {
+		t.Fatalf("got %+v", stats)
+	}
+	// 无法读取的目录下已有的条目保持不变
+	if _, ok := idx.Entries[filepath.Join(locked, "b.png")]; !ok {
+		t.Error("entry under the unreadable directory was removed")
+	}
+}
+
+func TestImageIndexDuplicatesTransitive(t *testing.T) {
+	rnd := rand.New(rand.NewSource(4))
+	a := randomHash(rnd, 64)
+	b := flipBits(rnd, a, 0)
+	// c 与 a 相差 6 位，d 在 a、c 之间，与二者各相差 3 位
+	bitList := make([]bool, 64)
+	for i := range bitList {
+		bitList[i] = a.Bit(i)
+	}
+	for i := 0; i < 3; i++ {
+		bitList[i] = !bitList[i]
+	}
+	d := newHash(bitList)
+	for i := 3; i < 6; i++ {
+		bitList[i] = !bitList[i]
+	}
+	c := newHash(bitList)
+	far := flipBits(rnd, a, 32)
+
+	idx := indexWithHashes(t, map[string]Hash{
+		"/img/a.png":   a,
+		"/img/b.png":   b,
+		"/img/c.png":   c,
+		"/img/d.png":   d,
+		"/img/far.png": far,
+		"/other/e.png": a,
+	})
+
+	groups := idx.Duplicates("/img", 3)
+	if len(groups) != 1 {
+		t.Fatalf("got %d groups, want 1: %v", len(groups), groups)
+	}
+	var paths []string
+	for _, m := range groups[0].Images {
+		paths = append(paths, m.Path)
+	}
+	want := []string{"/img/a.png", "/img/b.png", "/img/d.png", "/img/c.png"}
+	if len(paths) != len(want) {
+		t.Fatalf("group = %v, want %v", paths, want)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Fatalf("group = %v, want %v", paths, want)
+		}
+	}
+	if groups[0].Images[3].Distance != 6 {
+		t.Errorf("c is %d bits from the representative, want 6", groups[0].Images[3].Distance)
+	}
+
+	// 不限制目录时，其他目录下的相同图片也归入该组
+	if groups = idx.Duplicates("", 3); len(groups) != 1 || len(groups[0].Images) != 5 {
+		t.Errorf("without root: %v", groups)
+	}
+}
+
+func TestImageIndexRemembersFailedFiles(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	writeFixture(t, filepath.Join(dir, "a.png"), 1)
+	broken := filepath.Join(dir, "broken.jpg")
+	if err := ioutil.WriteFile(broken, []byte("not an image"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, _ := NewImageIndex("dhash", 8)
+	stats, err := idx.Update(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Added != 1 || stats.Failed != 1 {
+		t.Fatalf("first update: %+v", stats)
+	}
+	if e := idx.Entries[broken]; e == nil || !e.Failed {
+		t.Fatal("broken file has no failed marker")
+	}
+
+	// 失败条目在保存与加载后保留，文件未改变时不再重试
+	filename := filepath.Join(dir, "test.index")
+	if err = idx.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	if idx, err = LoadImageIndex(filename); err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ = idx.Update(dir); stats != (UpdateStats{}) {
+		t.Fatalf("second update retried unchanged files: %+v", stats)
+	}
+	if paths := idx.Paths(dir); len(paths) != 1 || paths[0] != filepath.Join(dir, "a.png") {
+		t.Errorf("Paths = %v, want only a.png", paths)
+	}
+	if groups := idx.Duplicates(dir, 64); groups != nil {
+		t.Errorf("failed entry was grouped: %v", groups)
+	}
+
+	// 文件改变后重新解码
+	writeFixture(t, broken, 2)
+	future := time.Now().Add(time.Hour)
+	if err = os.Chtimes(broken, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ = idx.Update(dir); stats.Updated != 1 || stats.Failed != 0 {
+		t.Fatalf("after fixing the file: %+v", stats)
+	}
+	if idx.Entries[broken].Failed {
+		t.Error("fixed file is still marked as failed")
+	}
+}
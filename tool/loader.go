@@ -2,12 +2,14 @@ package tool
 
 import (
 	"errors"
+	"fmt"
 	"image"
 	"image/color"
 	"image/jpeg"
 	"image/png"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -31,10 +33,10 @@ func (il *ImgLoader)decode(filePath string) (err error) {
 	il.filename = strings.Split(il.filename, ".")[0]
 	var img image.Image
 	img, il.format, err = image.Decode(file)
-	il.img = convertToNRGBA(img)
 	if err != nil {
 		return
 	}
+	il.img = convertToNRGBA(img)
 
 	return
 }
@@ -53,11 +55,12 @@ func NewImgLoader(filePath string) (il ImgLoader, err error) {
 		matrix[hi] = make([][]uint8, width)
 		for wi := range matrix[hi] {
 			matrix[hi][wi] = make([]uint8, 4)
-		    r, g, b ,a := il.img.At(wi, hi).RGBA()
-		    matrix[hi][wi][0] = uint8(r)
-			matrix[hi][wi][1] = uint8(g)
-		    matrix[hi][wi][2] = uint8(b)
-		    matrix[hi][wi][3] = uint8(a)
+			// 矩阵保存非预乘的颜色，RGBA() 返回的是 16 位预乘值，不能直接截断
+			c := color.NRGBAModel.Convert(il.img.At(wi, hi)).(color.NRGBA)
+			matrix[hi][wi][0] = c.R
+			matrix[hi][wi][1] = c.G
+			matrix[hi][wi][2] = c.B
+			matrix[hi][wi][3] = c.A
 		}
 	}
 
@@ -70,15 +73,15 @@ func (il *ImgLoader)GetImg() image.Image {
 	return il.img
 }
 
+// 返回像素矩阵的深拷贝，调用者可以任意修改而不影响 il
 func (il *ImgLoader)GetMatrix() [][][]uint8 {
-	matrix := NewRGBAMatrix(il.GetMY(), il.GetMY())
+	matrix := NewRGBAMatrix(il.GetMY(), il.GetMX())
 
-	copy(matrix, il.matrix)
-	//for hi := range matrix {
-	//	for wi := range matrix[hi] {
-	//		_ = copy(matrix[hi][wi], il.matrix[hi][wi])
-	//	}
-	//}
+	for hi := range matrix {
+		for wi := range matrix[hi] {
+			_ = copy(matrix[hi][wi], il.matrix[hi][wi])
+		}
+	}
 
 	return matrix
 }
@@ -104,11 +107,11 @@ func SaveAsPng(filename string, matrix [][][]uint8) (err error) {
 		return errors.New("not init yet")
 	}
 	height, width := len(matrix), len(matrix[0])
-	rgba := image.NewRGBA(image.Rect(0, 0, width, height))
+	rgba := image.NewNRGBA(image.Rect(0, 0, width, height))
 
 	for i := 0; i < height; i++ {
 		for j := 0; j < width; j++ {
-			rgba.Set(j, i, color.RGBA{
+			rgba.Set(j, i, color.NRGBA{
 				R: matrix[i][j][0],
 				G: matrix[i][j][1],
 				B: matrix[i][j][2],
@@ -162,6 +165,31 @@ func SaveAsJpeg(filename string, quality int, matrix [][][]uint8) (err error) {
 	return nil
 }
 
+// 以 1 bit 深度保存二值图，R 通道不小于 128 的像素视为白色
+func SaveAsBinaryPng(filename string, matrix [][][]uint8) (err error) {
+	if len(matrix) == 0 || len(matrix[0]) == 0 {
+		return errors.New("not init yet")
+	}
+	height, width := len(matrix), len(matrix[0])
+	bw := image.NewPaletted(image.Rect(0, 0, width, height), color.Palette{color.Black, color.White})
+
+	for i := 0; i < height; i++ {
+		for j := 0; j < width; j++ {
+			if matrix[i][j][0] >= 128 {
+				bw.SetColorIndex(j, i, 1)
+			}
+		}
+	}
+
+	outfile, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer outfile.Close()
+
+	return png.Encode(outfile, bw)
+}
+
 func NewRGBAMatrix(height, width int) [][][]uint8 {
 	matrix := make([][][]uint8, height)
 	for hi := range matrix {
@@ -340,3 +368,21 @@ func convertToNRGBA(src image.Image) *image.NRGBA {
 	return dst
 }
 
+
+// 解析 "rrggbb" 或 "rrggbbaa" 形式的十六进制颜色，可带 "#" 前缀
+func ParseHexColor(s string) (c color.RGBA, err error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) != 6 && len(s) != 8 {
+		return c, fmt.Errorf("invalid color: %s", s)
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return c, fmt.Errorf("invalid color: %s", s)
+	}
+	if len(s) == 6 {
+		v = v<<8 | 0xff
+	}
+
+	return color.RGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
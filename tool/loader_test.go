@@ -0,0 +1,19 @@
+package tool
+
+import "testing"
+
+func TestGetMatrixIsDeepCopy(t *testing.T) {
+	matrix := NewRGBAMatrix(2, 3)
+	matrix[1][2][0] = 10
+	il := &ImgLoader{matrix: matrix}
+
+	m := il.GetMatrix()
+	if len(m) != 2 || len(m[0]) != 3 {
+		t.Fatalf("got %dx%d matrix, want 2x3", len(m), len(m[0]))
+	}
+
+	m[1][2][0] = 200
+	if got := il.GetMatrix()[1][2][0]; got != 10 {
+		t.Errorf("modifying the copy changed the source pixel to %d", got)
+	}
+}
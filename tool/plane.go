@@ -0,0 +1,178 @@
+package tool
+
+import "math"
+
+// 单通道浮点平面，按 [行][列] 存储
+type plane [][]float64
+
+func newPlane(height, width int) plane {
+	p := make(plane, height)
+	for hi := range p {
+		p[hi] = make([]float64, width)
+	}
+
+	return p
+}
+
+// 按加权灰度公式得到亮度平面，取值范围 [0, 255]
+func grayPlane(matrix [][][]uint8) plane {
+	p := newPlane(len(matrix), len(matrix[0]))
+	for hi := range p {
+		for wi := range p[hi] {
+			p[hi][wi] = (float64(matrix[hi][wi][0])*30 + float64(matrix[hi][wi][1])*59 + float64(matrix[hi][wi][2])*11) / 100
+		}
+	}
+
+	return p
+}
+
+// 取出矩阵中的某个通道
+func channelPlane(matrix [][][]uint8, c int) plane {
+	p := newPlane(len(matrix), len(matrix[0]))
+	for hi := range p {
+		for wi := range p[hi] {
+			p[hi][wi] = float64(matrix[hi][wi][c])
+		}
+	}
+
+	return p
+}
+
+func clampUint8(v float64) uint8 {
+	if v < 0 {
+		return 0
+	} else if v > 255 {
+		return 255
+	}
+
+	return uint8(v + 0.5)
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	} else if v > hi {
+		return hi
+	}
+
+	return v
+}
+
+// 积分图，多出一行一列 0 以简化边界计算
+type integral struct {
+	sum   [][]float64
+	sqSum [][]float64
+}
+
+func newIntegral(p plane) integral {
+	height, width := len(p), len(p[0])
+	in := integral{sum: make([][]float64, height+1), sqSum: make([][]float64, height+1)}
+	for hi := range in.sum {
+		in.sum[hi] = make([]float64, width+1)
+		in.sqSum[hi] = make([]float64, width+1)
+	}
+
+	for hi := 0; hi < height; hi++ {
+		var rowSum, rowSqSum float64
+		for wi := 0; wi < width; wi++ {
+			rowSum += p[hi][wi]
+			rowSqSum += p[hi][wi] * p[hi][wi]
+			in.sum[hi+1][wi+1] = in.sum[hi][wi+1] + rowSum
+			in.sqSum[hi+1][wi+1] = in.sqSum[hi][wi+1] + rowSqSum
+		}
+	}
+
+	return in
+}
+
+// 以 (y, x) 为中心、半径为 r 的窗口内的均值与标准差，窗口超出边界的部分被裁掉
+func (in integral)meanStd(y, x, r int) (mean, std float64) {
+	height, width := len(in.sum)-1, len(in.sum[0])-1
+	y0, y1 := clampInt(y-r, 0, height), clampInt(y+r+1, 0, height)
+	x0, x1 := clampInt(x-r, 0, width), clampInt(x+r+1, 0, width)
+	n := float64((y1 - y0) * (x1 - x0))
+
+	sum := in.sum[y1][x1] - in.sum[y0][x1] - in.sum[y1][x0] + in.sum[y0][x0]
+	sqSum := in.sqSum[y1][x1] - in.sqSum[y0][x1] - in.sqSum[y1][x0] + in.sqSum[y0][x0]
+	mean = sum / n
+	variance := sqSum/n - mean*mean
+	if variance < 0 {
+		variance = 0
+	}
+
+	return mean, math.Sqrt(variance)
+}
+
+// 归一化的一维高斯核，半径取 3 sigma
+func gaussianKernel(sigma float64) []float64 {
+	r := int(math.Ceil(sigma * 3))
+	if r < 1 {
+		r = 1
+	}
+
+	kernel := make([]float64, 2*r+1)
+	var sum float64
+	for i := range kernel {
+		d := float64(i - r)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+
+	return kernel
+}
+
+// 可分离卷积，边界像素复制
+func (p plane)convolve(kernel []float64) plane {
+	height, width := len(p), len(p[0])
+	r := len(kernel) / 2
+
+	tmp := newPlane(height, width)
+	for hi := 0; hi < height; hi++ {
+		for wi := 0; wi < width; wi++ {
+			var v float64
+			for k := range kernel {
+				v += kernel[k] * p[hi][clampInt(wi+k-r, 0, width-1)]
+			}
+			tmp[hi][wi] = v
+		}
+	}
+
+	dst := newPlane(height, width)
+	for hi := 0; hi < height; hi++ {
+		for wi := 0; wi < width; wi++ {
+			var v float64
+			for k := range kernel {
+				v += kernel[k] * tmp[clampInt(hi+k-r, 0, height-1)][wi]
+			}
+			dst[hi][wi] = v
+		}
+	}
+
+	return dst
+}
+
+func (p plane)gaussianBlur(sigma float64) plane {
+	return p.convolve(gaussianKernel(sigma))
+}
+
+// Sobel 梯度幅值
+func (p plane)gradientMagnitude() plane {
+	height, width := len(p), len(p[0])
+	at := func(y, x int) float64 {
+		return p[clampInt(y, 0, height-1)][clampInt(x, 0, width-1)]
+	}
+
+	g := newPlane(height, width)
+	for hi := range g {
+		for wi := range g[hi] {
+			gx := at(hi-1, wi+1) + 2*at(hi, wi+1) + at(hi+1, wi+1) - at(hi-1, wi-1) - 2*at(hi, wi-1) - at(hi+1, wi-1)
+			gy := at(hi+1, wi-1) + 2*at(hi+1, wi) + at(hi+1, wi+1) - at(hi-1, wi-1) - 2*at(hi-1, wi) - at(hi-1, wi+1)
+			g[hi][wi] = math.Hypot(gx, gy)
+		}
+	}
+
+	return g
+}
@@ -3,6 +3,8 @@ package tool
 import (
 	"bytes"
 	"encoding/base64"
+	"errors"
+	"fmt"
 	"image/png"
 	"io/ioutil"
 	"math"
@@ -69,116 +71,6 @@ func (ip *ImgProcessor)NegativeFilmEffect(il *ImgLoader) *ImgLoader {
 	}
 }
 
-func (ip *ImgProcessor)Rotate(il *ImgLoader) *ImgLoader {
-	src := il.GetMatrix()
-	height := il.GetMY()
-	width := il.GetMX()
-	imgMatrix := NewRGBAMatrix(width, height)
-
-	for i := 0; i < width; i++{
-		for j := 0; j < height; j++{
-			imgMatrix[i][j] = src[j][i]
-		}
-	}
-
-	return &ImgLoader{
-		filename: il.GetFileName(),
-		format: il.GetFormat(),
-		matrix: imgMatrix,
-		img: il.GetImg(),
-	}
-}
-
-// 调整图片亮度，light 最小值为 0
-func (ip *ImgProcessor)AdjustBrightness(il *ImgLoader, light float64) *ImgLoader {
-	src := il.GetMatrix()
-
-	height := len(src)
-	width := len(src[0])
-	imgMatrix := NewRGBAMatrix(height, width)
-	copy(imgMatrix, src)
-
-	for i := 0; i < height; i++{
-		for j := 0; j < width; j++{
-			for c := 0; c < 3; c++ {
-				color := float64(imgMatrix[i][j][c]) * light - 100
-				if color < 0 {
-					color = 0
-				} else if color > 255 {
-					color = 255
-				}
-				imgMatrix[i][j][c] = uint8(color)
-			}
-		}
-	}
-
-	return &ImgLoader{
-		filename: il.GetFileName(),
-		format: il.GetFormat(),
-		matrix: imgMatrix,
-		img: il.GetImg(),
-	}
-}
-
-// 双线性插值法
-func (ip *ImgProcessor)Resize(il *ImgLoader, heigth, width int) *ImgLoader {
-	matrix := il.GetMatrix()
-
-	imgMatrix := NewRGBAMatrix(heigth, width)
-
-	for n := 0; n < 4; n++ {
-		for hi := range imgMatrix {
-			for wi := range imgMatrix[hi] {
-				srcY := (float64(hi) + 0.5) * (float64(il.GetMY()) / float64(heigth)) - 0.5
-				srcX := (float64(wi) + 0.5) * (float64(il.GetMX()) / float64(width) ) - 0.5
-				srcX0 := int(math.Floor(srcX))
-				if srcX0 < 0 {
-					srcX0 = 0
-				}
-				srcX1 := int(math.Min(float64(srcX0 + 1), float64(il.GetMX() - 1)))
-				srcY0 := int(math.Floor(srcY))
-				if srcY0 < 0 {
-					srcY0 = 0
-				}
-				srcY1 := int(math.Min(float64(srcY0 + 1), float64(il.GetMY() - 1)))
-
-				value0 := (float64(srcX1) - srcX) * float64(matrix[srcY0][srcX1][n]) + (srcX - float64(srcX0)) * float64(matrix[srcY0][srcX0][n])
-				value1 := (float64(srcX1) - srcX) * float64(matrix[srcY1][srcX1][n]) + (srcX - float64(srcX0)) * float64(matrix[srcY1][srcX0][n])
-				imgMatrix[hi][wi][n] = uint8((float64(srcY1) - srcY) * value1 + (srcY - float64(srcY0)) * value0)
-			}
-		}
-	}
-
-	return &ImgLoader{
-		filename: il.GetFileName(),
-		format: il.GetFormat(),
-		matrix: imgMatrix,
-		img: il.GetImg(),
-	}
-}
-
-// fuse two images(filepath) and the size of new image is as il1
-func (ip *ImgProcessor)ImageFusion(il1 *ImgLoader, il2 *ImgLoader) *ImgLoader {
-	imgMatrix1 := il1.GetMatrix()
-
-	height := len(il1.GetMatrix())
-	width := len(il1.GetMatrix()[0])
-
-	imgMatrix2 := ip.Resize(il2, height, width).GetMatrix()
-
-	for i := 0; i < height; i++{
-		for j := 0; j < width; j++{
-			imgMatrix1[i][j][0] = (imgMatrix1[i][j][0] >> 1) + (imgMatrix2[i][j][0] >> 1)
-			imgMatrix1[i][j][1] = (imgMatrix1[i][j][1] >> 1) + (imgMatrix2[i][j][1] >> 1)
-			imgMatrix1[i][j][2] = (imgMatrix1[i][j][2] >> 1) + (imgMatrix2[i][j][2] >> 1)
-		}
-	}
-
-	return &ImgLoader{
-		matrix: imgMatrix1,
-	}
-}
-
 func (ip *ImgProcessor)RGB2Gray(il *ImgLoader) *ImgLoader {
 	src := il.GetMatrix()
 	height := il.GetMY()
@@ -236,39 +128,50 @@ func (ip *ImgProcessor)Base642Img(filename string) (err error) {
 	return
 }
 
+// dHash 指纹，由 64 个 '0' / '1' 组成，第 i 位表示 8x9 灰度缩略图中对应像素是否比右邻更亮
 func (ip *ImgProcessor)GetFingerPrint(il *ImgLoader) (fp string) {
-	matrix := ip.Resize(il, 8, 9).GetMatrix()
+	hash := DifferenceHash{Size: 8}.Hash(il)
 
-	height := len(matrix)
-	width := len(matrix[0])
-
-	// convert rgb to gray
-	gray := make([]uint8, height * width)
-	for hi := 0; hi < height; hi++ {
-		for wi := 0; wi < width; wi++ {
-			gValue := (matrix[hi][wi][0] * 30 + matrix[hi][wi][1] * 59 + matrix[hi][wi][2] * 11) / 100
-			gray[9 * hi + wi] = gValue
+	var buf bytes.Buffer
+	for i := 0; i < hash.Len(); i++ {
+		if hash.Bit(i) {
+			buf.WriteByte('1')
+		} else {
+			buf.WriteByte('0')
 		}
 	}
 
-	var buf bytes.Buffer
-	for hi := 0; hi < height; hi++ {
-		for wi := 0; wi < width-1; wi++ {
-			diff := int16(gray[9 * hi + wi] - gray[9 * hi + wi + 1])
-			if diff > 0 {
-				buf.WriteByte('1')
-			} else {
-				buf.WriteByte('0')
-			}
+	return buf.String()
+}
+
+// 比较两个指纹，返回汉明距离与相似度（1 - 距离 / 位数）
+func CompareFingerprints(a, b string) (distance int, similarity float64, err error) {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0, 0, errors.New("fingerprints have different lengths")
+	}
+
+	for i := 0; i < len(a); i++ {
+		if (a[i] != '0' && a[i] != '1') || (b[i] != '0' && b[i] != '1') {
+			return 0, 0, fmt.Errorf("invalid fingerprint bit at %d", i)
+		}
+		if a[i] != b[i] {
+			distance++
 		}
 	}
 
-	return buf.String()
+	return distance, 1 - float64(distance)/float64(len(a)), nil
 }
 
 func GetActionList() []string {
 	return []string{"Sunset", "NegativeFilm", "Rotate", "AdjustBrightness", "Resize", "Base64Dec", "ToGray",
-		"Base64Enc", "Fusion", "FingerPrint", "ToASCII"}
+		"Base64Enc", "Fusion", "FingerPrint", "ToASCII", "Threshold", "AdaptiveThreshold",
+		"Histogram", "Equalize", "MatchHistogram", "CLAHE",
+		"AdjustContrast", "AdjustGamma", "AdjustExposure", "AutoLevels", "Levels", "Curves",
+		"ApplyLUT", "ExportLUT",
+		"HueRotate", "Saturation", "Vibrance", "SelectiveColor", "WhiteBalance",
+		"Sepia", "Vintage", "CrossProcess", "Duotone", "Solarize", "Posterize", "Emboss", "Vignette", "ContactSheet",
+		"Orientation", "RotateAngle", "Thumbnail",
+		"Crop", "Pad", "Canvas", "ROI", "Affine", "Perspective", "SeamCarve", "RemoveObject", "Watermark", "Text", "Draw", "Alpha", "ChromaKey", "Hash", "Compare", "Dedupe", "Quality"}
 }
 
 
@@ -0,0 +1,89 @@
+package tool
+
+import (
+	"strings"
+	"testing"
+)
+
+// 9x8 的测试图片，与 dHash 缩略图同尺寸，缩放不改变像素
+func fingerprintFixture(pixel func(wi, hi int) (r, g, b uint8)) *ImgLoader {
+	matrix := NewRGBAMatrix(8, 9)
+	for hi := range matrix {
+		for wi := range matrix[hi] {
+			r, g, b := pixel(wi, hi)
+			matrix[hi][wi][0], matrix[hi][wi][1], matrix[hi][wi][2], matrix[hi][wi][3] = r, g, b, 255
+		}
+	}
+
+	return &ImgLoader{filename: "fixture", matrix: matrix}
+}
+
+func TestGetFingerPrintGolden(t *testing.T) {
+	cases := []struct {
+		name  string
+		pixel func(wi, hi int) (r, g, b uint8)
+		want  string
+	}{
+		{
+			// 每行从左到右变暗；旧实现用 uint8 计算 r*30 会溢出
+			name: "darkening red",
+			pixel: func(wi, hi int) (r, g, b uint8) {
+				return uint8(250 - 25*wi), 0, 0
+			},
+			want: strings.Repeat("1", 64),
+		},
+		{
+			// 每行从左到右变亮；旧实现对 uint8 差值取 int16，左 < 右时也会得到 1
+			name: "brightening gray",
+			pixel: func(wi, hi int) (r, g, b uint8) {
+				v := uint8(20 + 25*wi)
+				return v, v, v
+			},
+			want: strings.Repeat("0", 64),
+		},
+		{
+			name: "pattern",
+			pixel: func(wi, hi int) (r, g, b uint8) {
+				v := uint8((wi*4+hi*7)%9*25 + 20)
+				return v, v, v
+			},
+			want: "0010101010101001100101010101010001001010101010101010010101010101",
+		},
+	}
+
+	ip := &ImgProcessor{}
+	for _, c := range cases {
+		if got := ip.GetFingerPrint(fingerprintFixture(c.pixel)); got != c.want {
+			t.Errorf("%s:\n got  %s\n want %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestCompareFingerprints(t *testing.T) {
+	a := "0010101010101001100101010101010001001010101010101010010101010101"
+	inverted := strings.Map(func(r rune) rune {
+		if r == '0' {
+			return '1'
+		}
+		return '0'
+	}, a)
+
+	if d, s, err := CompareFingerprints(a, a); err != nil || d != 0 || s != 1 {
+		t.Errorf("identical: got (%d, %v, %v), want (0, 1, nil)", d, s, err)
+	}
+	if d, s, err := CompareFingerprints(a, inverted); err != nil || d != 64 || s != 0 {
+		t.Errorf("inverted: got (%d, %v, %v), want (64, 0, nil)", d, s, err)
+	}
+
+	bad := [][2]string{
+		{a, a[:63]},
+		{"", ""},
+		{a, strings.Replace(a, "1", "2", 1)},
+		{strings.Replace(a, "0", "x", 1), a},
+	}
+	for _, c := range bad {
+		if _, _, err := CompareFingerprints(c[0], c[1]); err == nil {
+			t.Errorf("CompareFingerprints(%q, %q): expected an error", c[0], c[1])
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package tool
+
+import (
+	"errors"
+	"math"
+)
+
+// 全参考图像质量指标，ref 为参考图，img 为待评估的图
+type QualityReport struct {
+	MSE    float64
+	PSNR   float64 // 单位 dB，两图完全相同时为 +Inf
+	SSIM   float64
+	MSSSIM float64
+}
+
+// SSIM 的常数，L = 255，K1 = 0.01，K2 = 0.03，高斯窗口 sigma = 1.5
+const (
+	ssimC1    = (0.01 * 255) * (0.01 * 255)
+	ssimC2    = (0.03 * 255) * (0.03 * 255)
+	ssimSigma = 1.5
+)
+
+// MS-SSIM 五个尺度的权重（Wang 等，2003）
+var msssimWeights = []float64{0.0448, 0.2856, 0.3001, 0.2363, 0.1333}
+
+// 计算所有指标并返回 SSIM 图。autoResize 为 true 时，尺寸不同的 img 会先缩放到 ref 的大小，否则返回错误
+func (ip *ImgProcessor)Quality(ref, img *ImgLoader, autoResize bool) (QualityReport, *ImgLoader, error) {
+	img, err := ip.matchSize(ref, img, autoResize)
+	if err != nil {
+		return QualityReport{}, nil, err
+	}
+
+	var report QualityReport
+	report.MSE, _ = ip.MSE(ref, img)
+	report.PSNR, _ = ip.PSNR(ref, img)
+	report.MSSSIM, _ = ip.MSSSIM(ref, img)
+
+	ssim, _ := ssimMaps(grayPlane(ref.GetMatrix()), grayPlane(img.GetMatrix()))
+	report.SSIM = planeMean(ssim)
+
+	return report, ssimImage(ssim, img), nil
+}
+
+// R、G、B 三个通道上的均方误差
+func (ip *ImgProcessor)MSE(ref, img *ImgLoader) (float64, error) {
+	if ref.GetMX() != img.GetMX() || ref.GetMY() != img.GetMY() {
+		return 0, errors.New("images have different sizes")
+	}
+
+	a, b := ref.GetMatrix(), img.GetMatrix()
+	var sum float64
+	for hi := range a {
+		for wi := range a[hi] {
+			for c := 0; c < 3; c++ {
+				d := float64(a[hi][wi][c]) - float64(b[hi][wi][c])
+				sum += d * d
+			}
+		}
+	}
+
+	return sum / float64(3*ref.GetMX()*ref.GetMY()), nil
+}
+
+// 峰值信噪比 10 * log10(255^2 / MSE)
+func (ip *ImgProcessor)PSNR(ref, img *ImgLoader) (float64, error) {
+	mse, err := ip.MSE(ref, img)
+	if err != nil {
+		return 0, err
+	}
+	if mse == 0 {
+		return math.Inf(1), nil
+	}
+
+	return 10 * math.Log10(255*255/mse), nil
+}
+
+// 亮度上的结构相似度，取 SSIM 图的均值
+func (ip *ImgProcessor)SSIM(ref, img *ImgLoader) (float64, error) {
+	if ref.GetMX() != img.GetMX() || ref.GetMY() != img.GetMY() {
+		return 0, errors.New("images have different sizes")
+	}
+
+	ssim, _ := ssimMaps(grayPlane(ref.GetMatrix()), grayPlane(img.GetMatrix()))
+	return planeMean(ssim), nil
+}
+
+// 逐像素的 SSIM 图，[0, 1] 映射为黑到白（负值记为 0），越暗的地方失真越大
+func (ip *ImgProcessor)SSIMMap(ref, img *ImgLoader) (*ImgLoader, error) {
+	if ref.GetMX() != img.GetMX() || ref.GetMY() != img.GetMY() {
+		return nil, errors.New("images have different sizes")
+	}
+
+	ssim, _ := ssimMaps(grayPlane(ref.GetMatrix()), grayPlane(img.GetMatrix()))
+	return ssimImage(ssim, img), nil
+}
+
+func ssimImage(ssim plane, img *ImgLoader) *ImgLoader {
+	imgMatrix := NewRGBAMatrix(len(ssim), len(ssim[0]))
+	for hi := range imgMatrix {
+		for wi := range imgMatrix[hi] {
+			v := clampUint8(ssim[hi][wi] * 255)
+			imgMatrix[hi][wi][0], imgMatrix[hi][wi][1], imgMatrix[hi][wi][2], imgMatrix[hi][wi][3] = v, v, v, 255
+		}
+	}
+
+	return &ImgLoader{
+		filename: img.GetFileName(),
+		format: img.GetFormat(),
+		matrix: imgMatrix,
+		img: img.GetImg(),
+	}
+}
+
+// 多尺度 SSIM：每个尺度计算对比度-结构项，最粗的尺度再乘上亮度项，尺度间 2x2 平均下采样。
+// 图片太小时减少尺度数并重新归一化权重
+func (ip *ImgProcessor)MSSSIM(ref, img *ImgLoader) (float64, error) {
+	if ref.GetMX() != img.GetMX() || ref.GetMY() != img.GetMY() {
+		return 0, errors.New("images have different sizes")
+	}
+
+	// 高斯窗口半径为 3 sigma，最粗的尺度至少要能放下一个窗口
+	const minSize = 11
+	scales := 1
+	for w, h := ref.GetMX(), ref.GetMY(); scales < len(msssimWeights) && w/2 >= minSize && h/2 >= minSize; scales++ {
+		w, h = w/2, h/2
+	}
+	weights := msssimWeights[:scales]
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+
+	a, b := grayPlane(ref.GetMatrix()), grayPlane(img.GetMatrix())
+	result := 1.0
+	for i, w := range weights {
+		ssim, cs := ssimMaps(a, b)
+		v := planeMean(cs)
+		if i == scales-1 {
+			v = planeMean(ssim)
+		}
+		result *= math.Pow(math.Max(v, 0), w/total)
+
+		if i < scales-1 {
+			a, b = halvePlane(a), halvePlane(b)
+		}
+	}
+
+	return result, nil
+}
+
+// 匹配尺寸
+func (ip *ImgProcessor)matchSize(ref, img *ImgLoader, autoResize bool) (*ImgLoader, error) {
+	if ref.GetMX() == img.GetMX() && ref.GetMY() == img.GetMY() {
+		return img, nil
+	}
+	if !autoResize {
+		return nil, errors.New("images have different sizes")
+	}
+
+	return ip.ResizeWith(img, ref.GetMY(), ref.GetMX(), CatmullRom), nil
+}
+
+// 返回 SSIM 图与对比度-结构（cs）图，局部统计量由高斯加权得到
+func ssimMaps(a, b plane) (ssim, cs plane) {
+	height, width := len(a), len(a[0])
+	aa, bb, ab := newPlane(height, width), newPlane(height, width), newPlane(height, width)
+	for hi := range a {
+		for wi := range a[hi] {
+			aa[hi][wi] = a[hi][wi] * a[hi][wi]
+			bb[hi][wi] = b[hi][wi] * b[hi][wi]
+			ab[hi][wi] = a[hi][wi] * b[hi][wi]
+		}
+	}
+
+	kernel := gaussianKernel(ssimSigma)
+	muA, muB := a.convolve(kernel), b.convolve(kernel)
+	sAA, sBB, sAB := aa.convolve(kernel), bb.convolve(kernel), ab.convolve(kernel)
+
+	ssim, cs = newPlane(height, width), newPlane(height, width)
+	for hi := range ssim {
+		for wi := range ssim[hi] {
+			ma, mb := muA[hi][wi], muB[hi][wi]
+			varA := sAA[hi][wi] - ma*ma
+			varB := sBB[hi][wi] - mb*mb
+			cov := sAB[hi][wi] - ma*mb
+
+			cs[hi][wi] = (2*cov + ssimC2) / (varA + varB + ssimC2)
+			ssim[hi][wi] = (2*ma*mb + ssimC1) / (ma*ma + mb*mb + ssimC1) * cs[hi][wi]
+		}
+	}
+
+	return ssim, cs
+}
+
+func planeMean(p plane) float64 {
+	var sum float64
+	for _, row := range p {
+		for _, v := range row {
+			sum += v
+		}
+	}
+
+	return sum / float64(len(p)*len(p[0]))
+}
+
+// 2x2 平均下采样，奇数边长时丢弃最后一行或一列
+func halvePlane(p plane) plane {
+	dst := newPlane(len(p)/2, len(p[0])/2)
+	for hi := range dst {
+		for wi := range dst[hi] {
+			dst[hi][wi] = (p[2*hi][2*wi] + p[2*hi][2*wi+1] + p[2*hi+1][2*wi] + p[2*hi+1][2*wi+1]) / 4
+		}
+	}
+
+	return dst
+}
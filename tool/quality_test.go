@@ -0,0 +1,116 @@
+package tool
+
+import (
+	"math"
+	"testing"
+)
+
+func TestQualityIdentical(t *testing.T) {
+	il := hashFixture(64, 48)
+	report, ssimMap, err := new(ImgProcessor).Quality(il, il, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.MSE != 0 || !math.IsInf(report.PSNR, 1) {
+		t.Errorf("MSE %v, PSNR %v, want 0 and +Inf", report.MSE, report.PSNR)
+	}
+	if math.Abs(report.SSIM-1) > 1e-9 || math.Abs(report.MSSSIM-1) > 1e-9 {
+		t.Errorf("SSIM %v, MS-SSIM %v, want 1", report.SSIM, report.MSSSIM)
+	}
+	if ssimMap.GetMX() != 64 || ssimMap.GetMY() != 48 {
+		t.Errorf("SSIM map is %dx%d, want 64x48", ssimMap.GetMX(), ssimMap.GetMY())
+	}
+}
+
+func TestQualityConstantOffset(t *testing.T) {
+	ref := hashFixture(40, 30)
+	matrix := ref.GetMatrix()
+	for hi := range matrix {
+		for wi := range matrix[hi] {
+			for c := 0; c < 3; c++ {
+				// 夹在 [10, 245] 内，保证加减 10 不越界
+				v := clampInt(int(matrix[hi][wi][c]), 10, 245)
+				matrix[hi][wi][c] = uint8(v)
+			}
+		}
+	}
+	ref = &ImgLoader{matrix: matrix}
+
+	shifted := ref.GetMatrix()
+	for hi := range shifted {
+		for wi := range shifted[hi] {
+			for c := 0; c < 3; c++ {
+				if (hi+wi+c)%2 == 0 {
+					shifted[hi][wi][c] += 10
+				} else {
+					shifted[hi][wi][c] -= 10
+				}
+			}
+		}
+	}
+
+	ip := &ImgProcessor{}
+	mse, err := ip.MSE(ref, &ImgLoader{matrix: shifted})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mse != 100 {
+		t.Errorf("MSE = %v, want 100", mse)
+	}
+
+	psnr, _ := ip.PSNR(ref, &ImgLoader{matrix: shifted})
+	if want := 10 * math.Log10(255*255/100.0); math.Abs(psnr-want) > 1e-9 {
+		t.Errorf("PSNR = %v, want %v", psnr, want)
+	}
+}
+
+func TestQualitySizeMismatch(t *testing.T) {
+	ref := hashFixture(40, 30)
+	small := hashFixture(20, 15)
+	ip := &ImgProcessor{}
+
+	if _, _, err := ip.Quality(ref, small, false); err == nil {
+		t.Error("Quality: expected an error for different sizes")
+	}
+	if _, err := ip.MSE(ref, small); err == nil {
+		t.Error("MSE: expected an error for different sizes")
+	}
+	if _, err := ip.SSIM(ref, small); err == nil {
+		t.Error("SSIM: expected an error for different sizes")
+	}
+	if _, err := ip.MSSSIM(ref, small); err == nil {
+		t.Error("MSSSIM: expected an error for different sizes")
+	}
+
+	report, ssimMap, err := ip.Quality(ref, small, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ssimMap.GetMX() != 40 || ssimMap.GetMY() != 30 {
+		t.Errorf("SSIM map is %dx%d, want 40x30", ssimMap.GetMX(), ssimMap.GetMY())
+	}
+	if report.SSIM <= 0.5 || report.SSIM >= 1 {
+		t.Errorf("SSIM of the upscaled copy = %v", report.SSIM)
+	}
+}
+
+// 小于 22 像素时只能取一个尺度，此时 MS-SSIM 等于 SSIM
+func TestMSSSIMSmallImage(t *testing.T) {
+	ip := &ImgProcessor{}
+	for _, size := range [][2]int{{1, 1}, {7, 1}, {5, 3}, {21, 21}, {30, 12}} {
+		ref := hashFixture(size[0], size[1])
+		matrix := ref.GetMatrix()
+		matrix[0][0][0] ^= 0xff
+		img := &ImgLoader{matrix: matrix}
+
+		msssim, err := ip.MSSSIM(ref, img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ssim, _ := ip.SSIM(ref, img)
+		if math.IsNaN(msssim) || math.Abs(msssim-math.Max(ssim, 0)) > 1e-9 {
+			t.Errorf("%dx%d: MS-SSIM %v, SSIM %v", size[0], size[1], msssim, ssim)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package tool
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// 重采样滤波器，Support 为放大时卷积核的半径，缩小时按缩放比例加宽以避免混叠。
+// Support 为 0 表示最近邻
+type ResampleFilter struct {
+	Name    string
+	Support float64
+	Kernel  func(x float64) float64
+}
+
+var (
+	NearestNeighbor = ResampleFilter{Name: "nearest"}
+
+	// 盒式滤波，缩小时等价于按面积平均
+	Box = ResampleFilter{Name: "box", Support: 0.5, Kernel: func(x float64) float64 {
+		if math.Abs(x) <= 0.5 {
+			return 1
+		}
+		return 0
+	}}
+
+	Bilinear = ResampleFilter{Name: "bilinear", Support: 1, Kernel: func(x float64) float64 {
+		x = math.Abs(x)
+		if x < 1 {
+			return 1 - x
+		}
+		return 0
+	}}
+
+	CatmullRom = ResampleFilter{Name: "catmullrom", Support: 2, Kernel: func(x float64) float64 {
+		return bcSpline(x, 0, 0.5)
+	}}
+
+	MitchellNetravali = ResampleFilter{Name: "mitchell", Support: 2, Kernel: func(x float64) float64 {
+		return bcSpline(x, 1.0/3, 1.0/3)
+	}}
+
+	Lanczos2 = ResampleFilter{Name: "lanczos2", Support: 2, Kernel: func(x float64) float64 {
+		return lanczos(x, 2)
+	}}
+
+	Lanczos3 = ResampleFilter{Name: "lanczos3", Support: 3, Kernel: func(x float64) float64 {
+		return lanczos(x, 3)
+	}}
+)
+
+var resampleFilters = []ResampleFilter{NearestNeighbor, Box, Bilinear, CatmullRom, MitchellNetravali, Lanczos2, Lanczos3}
+
+// 按名字查找滤波器，名字不区分大小写，"area" 与 "box" 等价
+func ParseResampleFilter(name string) (ResampleFilter, error) {
+	name = strings.ToLower(name)
+	if name == "area" {
+		name = "box"
+	}
+
+	for _, filter := range resampleFilters {
+		if filter.Name == name {
+			return filter, nil
+		}
+	}
+
+	return Bilinear, fmt.Errorf("unknown resample filter: %s", name)
+}
+
+// 所有滤波器的名字
+func ResampleFilterNames() []string {
+	names := make([]string, 0, len(resampleFilters))
+	for _, filter := range resampleFilters {
+		names = append(names, filter.Name)
+	}
+
+	return names
+}
+
+// 双线性插值法
+func (ip *ImgProcessor)Resize(il *ImgLoader, height, width int) *ImgLoader {
+	return ip.ResizeWith(il, height, width, Bilinear)
+}
+
+// 使用指定滤波器缩放到 height x width，先水平后垂直做两次一维卷积。
+// 插值在预乘透明度后进行，避免透明区域的颜色渗入。height、width 不足 1 时按 1 处理
+func (ip *ImgProcessor)ResizeWith(il *ImgLoader, height, width int, filter ResampleFilter) *ImgLoader {
+	if height < 1 {
+		height = 1
+	}
+	if width < 1 {
+		width = 1
+	}
+
+	src := il.GetMatrix()
+	srcHeight, srcWidth := il.GetMY(), il.GetMX()
+
+	xWeights := resampleWeights(srcWidth, width, filter)
+	yWeights := resampleWeights(srcHeight, height, filter)
+
+	// 水平方向
+	tmp := make([][][4]float64, srcHeight)
+	for hi := range tmp {
+		tmp[hi] = make([][4]float64, width)
+		for wi, ws := range xWeights {
+			var v [4]float64
+			for k, w := range ws.weights {
+				p := src[hi][ws.start+k]
+				a := float64(p[3]) / 255
+				v[0] += w * float64(p[0]) * a
+				v[1] += w * float64(p[1]) * a
+				v[2] += w * float64(p[2]) * a
+				v[3] += w * float64(p[3])
+			}
+			tmp[hi][wi] = v
+		}
+	}
+
+	// 垂直方向
+	imgMatrix := NewRGBAMatrix(height, width)
+	for hi, ws := range yWeights {
+		for wi := range imgMatrix[hi] {
+			var v [4]float64
+			for k, w := range ws.weights {
+				p := tmp[ws.start+k][wi]
+				for c := range v {
+					v[c] += w * p[c]
+				}
+			}
+			if v[3] > 0 {
+				a := v[3] / 255
+				v[0], v[1], v[2] = v[0]/a, v[1]/a, v[2]/a
+			}
+			setPixel(imgMatrix[hi][wi], v)
+		}
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: imgMatrix,
+		img: il.GetImg(),
+	}
+}
+
+// 输出像素对应的输入区间 [start, start+len(weights)) 及归一化权重
+type resampleWeight struct {
+	start   int
+	weights []float64
+}
+
+func resampleWeights(srcSize, dstSize int, filter ResampleFilter) []resampleWeight {
+	scale := float64(srcSize) / float64(dstSize)
+	result := make([]resampleWeight, dstSize)
+
+	if filter.Support == 0 {
+		for i := range result {
+			j := clampInt(int((float64(i)+0.5)*scale), 0, srcSize-1)
+			result[i] = resampleWeight{start: j, weights: []float64{1}}
+		}
+		return result
+	}
+
+	// 缩小时按比例放宽卷积核
+	fscale := math.Max(scale, 1)
+	support := filter.Support * fscale
+
+	for i := range result {
+		center := (float64(i) + 0.5) * scale
+		left := clampInt(int(math.Floor(center-support)), 0, srcSize-1)
+		right := clampInt(int(math.Ceil(center+support)), left+1, srcSize)
+
+		weights := make([]float64, right-left)
+		var sum float64
+		for j := left; j < right; j++ {
+			w := filter.Kernel((float64(j) + 0.5 - center) / fscale)
+			weights[j-left] = w
+			sum += w
+		}
+
+		if sum == 0 {
+			// 卷积核在区间内全部为 0，退化为最近邻
+			for k := range weights {
+				weights[k] = 0
+			}
+			weights[clampInt(int(center)-left, 0, len(weights)-1)] = 1
+		} else {
+			for k := range weights {
+				weights[k] /= sum
+			}
+		}
+		result[i] = resampleWeight{start: left, weights: weights}
+	}
+
+	return result
+}
+
+// Mitchell-Netravali 提出的 BC 三次样条，B=0、C=0.5 即 Catmull-Rom
+func bcSpline(x, b, c float64) float64 {
+	x = math.Abs(x)
+	switch {
+	case x < 1:
+		return ((12-9*b-6*c)*x*x*x + (-18+12*b+6*c)*x*x + (6 - 2*b)) / 6
+	case x < 2:
+		return ((-b-6*c)*x*x*x + (6*b+30*c)*x*x + (-12*b-48*c)*x + (8*b + 24*c)) / 6
+	}
+
+	return 0
+}
+
+func lanczos(x, a float64) float64 {
+	x = math.Abs(x)
+	if x == 0 {
+		return 1
+	}
+	if x >= a {
+		return 0
+	}
+
+	px := math.Pi * x
+	return a * math.Sin(px) * math.Sin(px/a) / (px * px)
+}
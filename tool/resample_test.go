@@ -0,0 +1,105 @@
+package tool
+
+import "testing"
+
+func TestResizeWithClampsSize(t *testing.T) {
+	il := &ImgLoader{matrix: NewRGBAMatrix(4, 6)}
+	ip := &ImgProcessor{}
+
+	cases := []struct {
+		height, width int
+		wantH, wantW  int
+	}{
+		{0, 3, 1, 3},
+		{3, 0, 3, 1},
+		{-2, -5, 1, 1},
+	}
+	for _, c := range cases {
+		for _, filter := range []ResampleFilter{NearestNeighbor, Bilinear, Lanczos3} {
+			result := ip.ResizeWith(il, c.height, c.width, filter)
+			if result.GetMY() != c.wantH || result.GetMX() != c.wantW {
+				t.Errorf("%s %dx%d: got %dx%d, want %dx%d", filter.Name, c.height, c.width, result.GetMY(), result.GetMX(), c.wantH, c.wantW)
+			}
+		}
+	}
+}
+
+func grayMatrix(rows [][]uint8) [][][]uint8 {
+	matrix := NewRGBAMatrix(len(rows), len(rows[0]))
+	for hi := range rows {
+		for wi, v := range rows[hi] {
+			matrix[hi][wi][0], matrix[hi][wi][1], matrix[hi][wi][2], matrix[hi][wi][3] = v, v, v, 255
+		}
+	}
+
+	return matrix
+}
+
+// 2x2 放大到 4x4，输出像素中心落在源像素中心之间的 1/4、3/4 处。
+// 离得近的源像素权重应为 0.75；权重反了时第 1、2 列会互换
+func TestResizeBilinear(t *testing.T) {
+	il := &ImgLoader{matrix: grayMatrix([][]uint8{
+		{0, 200},
+		{100, 100},
+	})}
+	want := [][]uint8{
+		{0, 50, 150, 200},
+		{25, 62, 138, 175},
+		{75, 87, 112, 125},
+		{100, 100, 100, 100},
+	}
+
+	for _, resize := range []func(*ImgLoader, int, int) *ImgLoader{
+		new(ImgProcessor).Resize,
+		func(il *ImgLoader, h, w int) *ImgLoader { return new(ImgProcessor).ResizeWith(il, h, w, Bilinear) },
+	} {
+		matrix := resize(il, 4, 4).GetMatrix()
+		for hi := range want {
+			for wi := range want[hi] {
+				if d := int(matrix[hi][wi][0]) - int(want[hi][wi]); d < -1 || d > 1 {
+					t.Errorf("pixel (%d, %d) = %d, want %d", hi, wi, matrix[hi][wi][0], want[hi][wi])
+				}
+			}
+		}
+	}
+}
+
+// 1 像素的棋盘格缩小 3 倍。输出像素中心正好落在源像素中心上，
+// 若缩小时不放宽卷积核，只会取到单个黑或白像素；放宽后应得到接近 50% 的灰
+func TestResizeDownscaleAverages(t *testing.T) {
+	rows := make([][]uint8, 36)
+	for hi := range rows {
+		rows[hi] = make([]uint8, 36)
+		for wi := range rows[hi] {
+			if (hi+wi)%2 == 0 {
+				rows[hi][wi] = 255
+			}
+		}
+	}
+	il := &ImgLoader{matrix: grayMatrix(rows)}
+
+	for _, filter := range []ResampleFilter{Bilinear, Lanczos3} {
+		matrix := new(ImgProcessor).ResizeWith(il, 12, 12, filter).GetMatrix()
+		for hi := range matrix {
+			for wi := range matrix[hi] {
+				if v := int(matrix[hi][wi][0]); v < 123 || v > 132 {
+					t.Fatalf("%s: pixel (%d, %d) = %d, want about 128", filter.Name, hi, wi, v)
+				}
+			}
+		}
+	}
+
+	// 盒式滤波等价于 3x3 区域的平均：5 白 4 黑或 4 白 5 黑
+	matrix := new(ImgProcessor).ResizeWith(il, 12, 12, Box).GetMatrix()
+	for hi := range matrix {
+		for wi := range matrix[hi] {
+			want := uint8(113)
+			if (hi+wi)%2 == 0 {
+				want = 142
+			}
+			if matrix[hi][wi][0] != want {
+				t.Fatalf("box: pixel (%d, %d) = %d, want %d", hi, wi, matrix[hi][wi][0], want)
+			}
+		}
+	}
+}
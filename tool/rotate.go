@@ -0,0 +1,199 @@
+package tool
+
+import (
+	"image/color"
+	"math"
+)
+
+// 几何变换中的插值方式
+type Interpolation int
+
+const (
+	InterpNearest Interpolation = iota
+	InterpBilinear
+	InterpBicubic
+)
+
+// 顺时针旋转 90 度
+func (ip *ImgProcessor)Rotate(il *ImgLoader) *ImgLoader {
+	return ip.Rotate90(il)
+}
+
+// 顺时针旋转 90 度
+func (ip *ImgProcessor)Rotate90(il *ImgLoader) *ImgLoader {
+	height := il.GetMY()
+	return remap(il, il.GetMX(), height, func(hi, wi int) (int, int) {
+		return height - 1 - wi, hi
+	})
+}
+
+func (ip *ImgProcessor)Rotate180(il *ImgLoader) *ImgLoader {
+	height, width := il.GetMY(), il.GetMX()
+	return remap(il, height, width, func(hi, wi int) (int, int) {
+		return height - 1 - hi, width - 1 - wi
+	})
+}
+
+// 顺时针旋转 270 度，即逆时针旋转 90 度
+func (ip *ImgProcessor)Rotate270(il *ImgLoader) *ImgLoader {
+	width := il.GetMX()
+	return remap(il, width, il.GetMY(), func(hi, wi int) (int, int) {
+		return wi, width - 1 - hi
+	})
+}
+
+// 水平翻转（左右镜像）
+func (ip *ImgProcessor)FlipHorizontal(il *ImgLoader) *ImgLoader {
+	width := il.GetMX()
+	return remap(il, il.GetMY(), width, func(hi, wi int) (int, int) {
+		return hi, width - 1 - wi
+	})
+}
+
+// 垂直翻转（上下镜像）
+func (ip *ImgProcessor)FlipVertical(il *ImgLoader) *ImgLoader {
+	height := il.GetMY()
+	return remap(il, height, il.GetMX(), func(hi, wi int) (int, int) {
+		return height - 1 - hi, wi
+	})
+}
+
+// 沿主对角线翻转
+func (ip *ImgProcessor)Transpose(il *ImgLoader) *ImgLoader {
+	return remap(il, il.GetMX(), il.GetMY(), func(hi, wi int) (int, int) {
+		return wi, hi
+	})
+}
+
+// 沿副对角线翻转
+func (ip *ImgProcessor)Transverse(il *ImgLoader) *ImgLoader {
+	height, width := il.GetMY(), il.GetMX()
+	return remap(il, width, height, func(hi, wi int) (int, int) {
+		return height - 1 - wi, width - 1 - hi
+	})
+}
+
+// 任意角度旋转，degrees 为正时顺时针。
+// 画布之外的区域以 bg 填充，bg.A 为 0 时为透明；expand 为 true 时扩大画布以容纳整幅图，否则保持原尺寸并裁掉超出部分
+func (ip *ImgProcessor)RotateAngle(il *ImgLoader, degrees float64, interp Interpolation, bg color.RGBA, expand bool) *ImgLoader {
+	src := il.GetMatrix()
+	height, width := il.GetMY(), il.GetMX()
+
+	rad := degrees * math.Pi / 180
+	sin, cos := math.Sin(rad), math.Cos(rad)
+
+	outHeight, outWidth := height, width
+	if expand {
+		outWidth = int(math.Ceil(math.Abs(float64(width)*cos) + math.Abs(float64(height)*sin) - 1e-9))
+		outHeight = int(math.Ceil(math.Abs(float64(width)*sin) + math.Abs(float64(height)*cos) - 1e-9))
+	}
+
+	cx, cy := float64(width-1)/2, float64(height-1)/2
+	ocx, ocy := float64(outWidth-1)/2, float64(outHeight-1)/2
+
+	imgMatrix := NewRGBAMatrix(outHeight, outWidth)
+	for hi := range imgMatrix {
+		for wi := range imgMatrix[hi] {
+			dx, dy := float64(wi)-ocx, float64(hi)-ocy
+			sx := dx*cos + dy*sin + cx
+			sy := -dx*sin + dy*cos + cy
+			setPixel(imgMatrix[hi][wi], sample(src, sx, sy, interp, bg))
+		}
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: imgMatrix,
+		img: il.GetImg(),
+	}
+}
+
+// 按坐标映射重排像素，fn 给出输出 (hi, wi) 对应的原图坐标
+func remap(il *ImgLoader, height, width int, fn func(hi, wi int) (int, int)) *ImgLoader {
+	src := il.GetMatrix()
+	imgMatrix := NewRGBAMatrix(height, width)
+
+	for hi := range imgMatrix {
+		for wi := range imgMatrix[hi] {
+			sy, sx := fn(hi, wi)
+			copy(imgMatrix[hi][wi], src[sy][sx])
+		}
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: imgMatrix,
+		img: il.GetImg(),
+	}
+}
+
+// 在原图的 (x, y) 处采样，坐标以像素中心为整数点。
+// 越界的邻居取 bg，插值在预乘透明度后进行，避免透明边缘出现色边
+func sample(src [][][]uint8, x, y float64, interp Interpolation, bg color.RGBA) [4]float64 {
+	height, width := len(src), len(src[0])
+
+	at := func(yi, xi int) [4]float64 {
+		if yi < 0 || yi >= height || xi < 0 || xi >= width {
+			a := float64(bg.A) / 255
+			return [4]float64{float64(bg.R) * a, float64(bg.G) * a, float64(bg.B) * a, float64(bg.A)}
+		}
+		p := src[yi][xi]
+		a := float64(p[3]) / 255
+		return [4]float64{float64(p[0]) * a, float64(p[1]) * a, float64(p[2]) * a, float64(p[3])}
+	}
+
+	var v [4]float64
+	switch interp {
+	case InterpNearest:
+		v = at(int(math.Floor(y+0.5)), int(math.Floor(x+0.5)))
+	case InterpBicubic:
+		x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+		fx, fy := x-float64(x0), y-float64(y0)
+		for m := -1; m <= 2; m++ {
+			wy := cubicWeight(float64(m) - fy)
+			for n := -1; n <= 2; n++ {
+				w := wy * cubicWeight(float64(n)-fx)
+				p := at(y0+m, x0+n)
+				for c := range v {
+					v[c] += w * p[c]
+				}
+			}
+		}
+	default:
+		x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+		fx, fy := x-float64(x0), y-float64(y0)
+		p00, p01, p10, p11 := at(y0, x0), at(y0, x0+1), at(y0+1, x0), at(y0+1, x0+1)
+		for c := range v {
+			v[c] = (p00[c]*(1-fx)+p01[c]*fx)*(1-fy) + (p10[c]*(1-fx)+p11[c]*fx)*fy
+		}
+	}
+
+	// 还原为非预乘
+	if v[3] > 0 {
+		a := v[3] / 255
+		v[0], v[1], v[2] = v[0]/a, v[1]/a, v[2]/a
+	}
+
+	return v
+}
+
+// Catmull-Rom 三次卷积核
+func cubicWeight(t float64) float64 {
+	t = math.Abs(t)
+	switch {
+	case t < 1:
+		return 1.5*t*t*t - 2.5*t*t + 1
+	case t < 2:
+		return -0.5*t*t*t + 2.5*t*t - 4*t + 2
+	}
+
+	return 0
+}
+
+func setPixel(pixel []uint8, v [4]float64) {
+	for c := range v {
+		pixel[c] = clampUint8(v[c])
+	}
+}
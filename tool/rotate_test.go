@@ -0,0 +1,59 @@
+package tool
+
+import "testing"
+
+// 2 行 3 列的测试图片，R 通道依次为 1 到 6：
+//
+//	1 2 3
+//	4 5 6
+func orientationFixture() *ImgLoader {
+	matrix := NewRGBAMatrix(2, 3)
+	for hi := range matrix {
+		for wi := range matrix[hi] {
+			matrix[hi][wi][0], matrix[hi][wi][3] = uint8(hi*3+wi+1), 255
+		}
+	}
+
+	return &ImgLoader{filename: "fixture", matrix: matrix}
+}
+
+func TestOrientations(t *testing.T) {
+	ip := &ImgProcessor{}
+	cases := []struct {
+		name string
+		fn   func(*ImgLoader) *ImgLoader
+		want [][]uint8
+	}{
+		{"Rotate90", ip.Rotate90, [][]uint8{{4, 1}, {5, 2}, {6, 3}}},
+		{"Rotate", ip.Rotate, [][]uint8{{4, 1}, {5, 2}, {6, 3}}},
+		{"Rotate180", ip.Rotate180, [][]uint8{{6, 5, 4}, {3, 2, 1}}},
+		{"Rotate270", ip.Rotate270, [][]uint8{{3, 6}, {2, 5}, {1, 4}}},
+		{"FlipHorizontal", ip.FlipHorizontal, [][]uint8{{3, 2, 1}, {6, 5, 4}}},
+		{"FlipVertical", ip.FlipVertical, [][]uint8{{4, 5, 6}, {1, 2, 3}}},
+		{"Transpose", ip.Transpose, [][]uint8{{1, 4}, {2, 5}, {3, 6}}},
+		{"Transverse", ip.Transverse, [][]uint8{{6, 3}, {5, 2}, {4, 1}}},
+	}
+
+	for _, c := range cases {
+		il := orientationFixture()
+		got := c.fn(il)
+		if got.GetMY() != len(c.want) || got.GetMX() != len(c.want[0]) {
+			t.Errorf("%s: got %dx%d, want %dx%d", c.name, got.GetMX(), got.GetMY(), len(c.want[0]), len(c.want))
+			continue
+		}
+
+		matrix := got.GetMatrix()
+		for hi := range c.want {
+			for wi := range c.want[hi] {
+				if p := matrix[hi][wi]; p[0] != c.want[hi][wi] || p[3] != 255 {
+					t.Errorf("%s: pixel (%d, %d) = %v, want %d", c.name, hi, wi, p, c.want[hi][wi])
+				}
+			}
+		}
+
+		// 源图不应被改写
+		if il.GetMatrix()[0][1][0] != 2 {
+			t.Errorf("%s modified its input", c.name)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package tool
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// 缝裁剪的约束掩码，与图片同尺寸：正值表示保护，负值表示优先删除，0 表示无约束
+type SeamMask [][]int8
+
+// 掩码能量的权重，远大于梯度幅值的取值范围
+const maskEnergy = 1e6
+
+// 放大时在已复制过的像素的掩码上加上该值，使其在后续轮次中受保护，放大结束后再减去。
+// 用户掩码只取 -1、0、1，因此加上后的值不小于 insertedMark-1，不会与原值混淆
+const insertedMark = 4
+
+// 由标记图生成掩码：偏绿的像素保护，偏红的像素删除，其余无约束
+func MaskFromImage(il *ImgLoader) SeamMask {
+	matrix := il.GetMatrix()
+	mask := make(SeamMask, len(matrix))
+	for hi := range matrix {
+		mask[hi] = make([]int8, len(matrix[hi]))
+		for wi, p := range matrix[hi] {
+			switch {
+			case p[1] > 160 && p[0] < 100 && p[2] < 100:
+				mask[hi][wi] = 1
+			case p[0] > 160 && p[1] < 100 && p[2] < 100:
+				mask[hi][wi] = -1
+			}
+		}
+	}
+
+	return mask
+}
+
+// 基于内容的缩放：反复删除或插入能量最低的缝，把图片改为 width x height。
+// 能量为亮度梯度幅值，mask 可为 nil
+func (ip *ImgProcessor)SeamCarve(il *ImgLoader, width, height int, mask SeamMask) (*ImgLoader, error) {
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("height or width has to greater than 0")
+	}
+	if mask != nil && (len(mask) != il.GetMY() || len(mask[0]) != il.GetMX()) {
+		return nil, errors.New("mask size does not match the image")
+	}
+
+	c := newCarver(il.GetMatrix(), mask)
+	c.resizeWidth(width)
+	c.transpose()
+	c.resizeWidth(height)
+	c.transpose()
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: c.matrix,
+		img: il.GetImg(),
+	}, nil
+}
+
+// 去除物体：不断删除穿过掩码负值区域的竖直缝，直到负值区域消失，再插入同样数量的缝恢复原宽度
+func (ip *ImgProcessor)RemoveObject(il *ImgLoader, mask SeamMask) (*ImgLoader, error) {
+	if mask == nil || len(mask) != il.GetMY() || len(mask[0]) != il.GetMX() {
+		return nil, errors.New("mask size does not match the image")
+	}
+
+	c := newCarver(il.GetMatrix(), mask)
+	removed := 0
+	for c.hasRemoval() && c.width() > 1 {
+		c.removeSeam(c.findSeam())
+		removed++
+	}
+	if removed == 0 {
+		return nil, errors.New("mask has no region to remove")
+	}
+	c.resizeWidth(il.GetMX())
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: c.matrix,
+		img: il.GetImg(),
+	}, nil
+}
+
+type carver struct {
+	matrix [][][]uint8
+	mask   SeamMask
+}
+
+func newCarver(matrix [][][]uint8, mask SeamMask) *carver {
+	c := &carver{matrix: matrix, mask: make(SeamMask, len(matrix))}
+	for hi := range c.mask {
+		c.mask[hi] = make([]int8, len(matrix[hi]))
+		if mask != nil {
+			copy(c.mask[hi], mask[hi])
+		}
+	}
+
+	return c
+}
+
+func (c *carver)width() int {
+	return len(c.matrix[0])
+}
+
+func (c *carver)hasRemoval() bool {
+	for _, row := range c.mask {
+		for _, v := range row {
+			if v < 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (c *carver)resizeWidth(target int) {
+	for c.width() > target {
+		c.removeSeam(c.findSeam())
+	}
+
+	// 一次插入过多的缝会集中在同一片低能量区域，因此每轮至多插入当前宽度的一半
+	for c.width() < target {
+		n := target - c.width()
+		if n > c.width()/2 {
+			n = int(math.Max(1, float64(c.width()/2)))
+		}
+		c.insertSeams(n)
+	}
+
+	for hi := range c.mask {
+		for wi, v := range c.mask[hi] {
+			if v >= insertedMark-1 {
+				c.mask[hi][wi] = v - insertedMark
+			}
+		}
+	}
+}
+
+func (c *carver)energy() plane {
+	e := grayPlane(c.matrix).gradientMagnitude()
+	for hi := range e {
+		for wi := range e[hi] {
+			v := c.mask[hi][wi]
+			if v >= insertedMark-1 {
+				v = 1
+			}
+			e[hi][wi] += float64(v) * maskEnergy
+		}
+	}
+
+	return e
+}
+
+// 动态规划求能量和最小的竖直缝，seam[y] 为第 y 行被选中的列
+func (c *carver)findSeam() []int {
+	e := c.energy()
+	height, width := len(e), len(e[0])
+
+	cost := newPlane(height, width)
+	copy(cost[0], e[0])
+	for hi := 1; hi < height; hi++ {
+		for wi := 0; wi < width; wi++ {
+			best := cost[hi-1][wi]
+			if wi > 0 && cost[hi-1][wi-1] < best {
+				best = cost[hi-1][wi-1]
+			}
+			if wi < width-1 && cost[hi-1][wi+1] < best {
+				best = cost[hi-1][wi+1]
+			}
+			cost[hi][wi] = e[hi][wi] + best
+		}
+	}
+
+	seam := make([]int, height)
+	for wi := 1; wi < width; wi++ {
+		if cost[height-1][wi] < cost[height-1][seam[height-1]] {
+			seam[height-1] = wi
+		}
+	}
+	for hi := height - 2; hi >= 0; hi-- {
+		x := seam[hi+1]
+		seam[hi] = x
+		for _, nx := range []int{x - 1, x + 1} {
+			if nx >= 0 && nx < width && cost[hi][nx] < cost[hi][seam[hi]] {
+				seam[hi] = nx
+			}
+		}
+	}
+
+	return seam
+}
+
+func (c *carver)removeSeam(seam []int) {
+	for hi, x := range seam {
+		c.matrix[hi] = append(c.matrix[hi][:x], c.matrix[hi][x+1:]...)
+		c.mask[hi] = append(c.mask[hi][:x], c.mask[hi][x+1:]...)
+	}
+}
+
+// 在副本上依次找出 n 条最低能量的缝并记下它们在当前图中的位置，
+// 再把这些位置的像素复制一份（取与右邻的平均值）插入。
+// 原像素与插入的像素都被标记为受保护，避免下一轮再次拉伸同一片区域
+func (c *carver)insertSeams(n int) {
+	height := len(c.matrix)
+
+	// index[y][x] 记录副本中像素在当前图中的列号
+	tmp := &carver{matrix: make([][][]uint8, height), mask: make(SeamMask, height)}
+	index := make([][]int, height)
+	for hi := range index {
+		tmp.matrix[hi] = append([][]uint8(nil), c.matrix[hi]...)
+		tmp.mask[hi] = append([]int8(nil), c.mask[hi]...)
+		index[hi] = make([]int, c.width())
+		for wi := range index[hi] {
+			index[hi][wi] = wi
+		}
+	}
+
+	positions := make([][]int, height)
+	for i := 0; i < n; i++ {
+		seam := tmp.findSeam()
+		for hi, x := range seam {
+			positions[hi] = append(positions[hi], index[hi][x])
+			index[hi] = append(index[hi][:x], index[hi][x+1:]...)
+		}
+		tmp.removeSeam(seam)
+	}
+
+	// 逐行替换时 c.width() 会随第 0 行改变，先记下原宽度
+	width := c.width()
+	for hi := range c.matrix {
+		sort.Ints(positions[hi])
+		row := make([][]uint8, 0, width+n)
+		maskRow := make([]int8, 0, width+n)
+		k := 0
+		for wi, p := range c.matrix[hi] {
+			row = append(row, p)
+			v := c.mask[hi][wi]
+			if k < len(positions[hi]) && positions[hi][k] == wi && v < insertedMark-1 {
+				v += insertedMark
+			}
+			maskRow = append(maskRow, v)
+			for k < len(positions[hi]) && positions[hi][k] == wi {
+				right := c.matrix[hi][clampInt(wi+1, 0, width-1)]
+				dup := make([]uint8, 4)
+				for ch := range dup {
+					dup[ch] = uint8((int(p[ch]) + int(right[ch]) + 1) / 2)
+				}
+				row = append(row, dup)
+				maskRow = append(maskRow, v)
+				k++
+			}
+		}
+		c.matrix[hi] = row
+		c.mask[hi] = maskRow
+	}
+}
+
+func (c *carver)transpose() {
+	height, width := len(c.matrix), c.width()
+	matrix := make([][][]uint8, width)
+	mask := make(SeamMask, width)
+	for wi := range matrix {
+		matrix[wi] = make([][]uint8, height)
+		mask[wi] = make([]int8, height)
+		for hi := range matrix[wi] {
+			matrix[wi][hi] = c.matrix[hi][wi]
+			mask[wi][hi] = c.mask[hi][wi]
+		}
+	}
+
+	c.matrix, c.mask = matrix, mask
+}
@@ -0,0 +1,92 @@
+package tool
+
+import "testing"
+
+func stripeMatrix(height, width int) [][][]uint8 {
+	matrix := NewRGBAMatrix(height, width)
+	for hi := range matrix {
+		for wi := range matrix[hi] {
+			v := uint8(0)
+			// 右半部分为高频条纹，左半部分平坦，缝应优先落在左半部分
+			if wi >= width/2 && wi%2 == 0 {
+				v = 255
+			}
+			matrix[hi][wi][0], matrix[hi][wi][1], matrix[hi][wi][2], matrix[hi][wi][3] = v, v, v, 255
+		}
+	}
+
+	return matrix
+}
+
+func countMarked(row []int8) int {
+	n := 0
+	for _, v := range row {
+		if v >= insertedMark-1 {
+			n++
+		}
+	}
+
+	return n
+}
+
+func TestInsertSeamsProtectsCopiedPixels(t *testing.T) {
+	c := newCarver(stripeMatrix(6, 20), nil)
+
+	c.insertSeams(5)
+	for hi, row := range c.mask {
+		if n := countMarked(row); n != 10 {
+			t.Fatalf("row %d: %d marked pixels after the first round, want 10", hi, n)
+		}
+	}
+
+	// 第二轮的缝必须避开已标记的像素，因此标记数再增加 2 * 5
+	c.insertSeams(5)
+	for hi, row := range c.mask {
+		if n := countMarked(row); n != 20 {
+			t.Fatalf("row %d: %d marked pixels after the second round, want 20", hi, n)
+		}
+	}
+}
+
+func TestResizeWidthRestoresMask(t *testing.T) {
+	mask := make(SeamMask, 6)
+	for hi := range mask {
+		mask[hi] = make([]int8, 20)
+		mask[hi][15] = 1
+	}
+	c := newCarver(stripeMatrix(6, 20), mask)
+
+	c.resizeWidth(70)
+	for hi, row := range c.mask {
+		if len(row) != 70 {
+			t.Fatalf("row %d: width %d, want 70", hi, len(row))
+		}
+		protected := 0
+		for _, v := range row {
+			if v < -1 || v > 1 {
+				t.Fatalf("row %d: mask value %d left after enlarging", hi, v)
+			}
+			if v == 1 {
+				protected++
+			}
+		}
+		if protected < 1 {
+			t.Fatalf("row %d: user protection lost", hi)
+		}
+	}
+}
+
+func TestSeamCarveSize(t *testing.T) {
+	il := &ImgLoader{matrix: stripeMatrix(10, 16)}
+	ip := &ImgProcessor{}
+
+	for _, size := range [][2]int{{8, 10}, {16, 5}, {40, 25}} {
+		result, err := ip.SeamCarve(il, size[0], size[1], nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.GetMX() != size[0] || result.GetMY() != size[1] {
+			t.Errorf("SeamCarve to %dx%d: got %dx%d", size[0], size[1], result.GetMX(), result.GetMY())
+		}
+	}
+}
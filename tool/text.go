@@ -0,0 +1,314 @@
+package tool
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"math"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+type TextAlign int
+
+const (
+	AlignLeft TextAlign = iota
+	AlignCenter
+	AlignRight
+)
+
+var textAlignNames = []string{"left", "center", "right"}
+
+func (align TextAlign)String() string {
+	if align < AlignLeft || align > AlignRight {
+		return "unknown"
+	}
+
+	return textAlignNames[align]
+}
+
+func ParseTextAlign(name string) (TextAlign, error) {
+	for i, v := range textAlignNames {
+		if strings.ToLower(name) == v {
+			return TextAlign(i), nil
+		}
+	}
+
+	return AlignLeft, fmt.Errorf("unknown text align: %s", name)
+}
+
+func TextAlignNames() []string {
+	return textAlignNames
+}
+
+// 文字样式，颜色的 A 分量即不透明度
+type TextStyle struct {
+	Font        *opentype.Font // 为 nil 时使用内置的 7x13 点阵字体（仅含 ASCII）
+	Size        float64        // 字号（像素），点阵字体按 Size/13 取整倍放大
+	Color       color.RGBA
+	StrokeWidth int            // 描边宽度（像素），0 为不描边
+	StrokeColor color.RGBA
+	ShadowX     int            // 阴影偏移，均为 0 时不画阴影
+	ShadowY     int
+	ShadowColor color.RGBA
+	Align       TextAlign
+	LineSpacing float64        // 行高相对字体行高的倍数，不大于 0 时为 1
+}
+
+func DefaultTextStyle() TextStyle {
+	return TextStyle{
+		Size: 26,
+		Color: color.RGBA{R: 255, G: 255, B: 255, A: 255},
+		StrokeColor: color.RGBA{A: 255},
+		ShadowColor: color.RGBA{A: 128},
+		LineSpacing: 1.2,
+	}
+}
+
+// 从 TrueType / OpenType 字体文件（也支持 .ttc 字体集合，取其中第一个）加载字体，字号在绘制时由 TextStyle.Size 决定
+func LoadFont(filename string) (*opentype.Font, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := opentype.Parse(data)
+	if err != nil {
+		collection, errC := opentype.ParseCollection(data)
+		if errC != nil {
+			return nil, err
+		}
+		if f, err = collection.Font(0); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// 在 box 内绘制文字，超出 box 宽度时自动换行（英文按单词，中日韩文字按字），超出高度的行被丢弃。
+// box 为零值时使用整幅图片，与图片不相交时不绘制，text 中的 "\n" 为强制换行
+func (ip *ImgProcessor)DrawText(il *ImgLoader, text string, box image.Rectangle, style TextStyle) *ImgLoader {
+	imgMatrix := il.GetMatrix()
+	bounds := image.Rect(0, 0, il.GetMX(), il.GetMY())
+	if box == (image.Rectangle{}) {
+		box = bounds
+	}
+	box = box.Intersect(bounds)
+	if box.Empty() {
+		return &ImgLoader{
+			filename: il.GetFileName(),
+			format: il.GetFormat(),
+			matrix: imgMatrix,
+			img: il.GetImg(),
+		}
+	}
+
+	// 点阵字体先按原尺寸绘制，再整数倍放大
+	var face font.Face
+	scale := 1
+	if style.Font != nil {
+		// NewFace 只保存参数，不会返回错误
+		face, _ = opentype.NewFace(style.Font, &opentype.FaceOptions{Size: math.Max(1, style.Size), DPI: 72, Hinting: font.HintingFull})
+	} else {
+		face = basicfont.Face7x13
+		scale = int(math.Max(1, math.Round(style.Size/13)))
+	}
+	width, height := (box.Dx()+scale-1)/scale, (box.Dy()+scale-1)/scale
+
+	mask := textMask(face, wrapText(face, text, width), width, height, style.Align, style.LineSpacing)
+	if scale > 1 {
+		mask = scaleMask(mask, scale)
+	}
+
+	var stroke *image.Alpha
+	if style.StrokeWidth > 0 {
+		stroke = dilateMask(mask, style.StrokeWidth)
+	}
+
+	// 由下到上依次为阴影、描边、文字
+	if style.ShadowX != 0 || style.ShadowY != 0 {
+		shadow := mask
+		if stroke != nil {
+			shadow = stroke
+		}
+		fillMask(imgMatrix, shadow, box.Min.X+style.ShadowX, box.Min.Y+style.ShadowY, style.ShadowColor)
+	}
+	if stroke != nil {
+		fillMask(imgMatrix, stroke, box.Min.X, box.Min.Y, style.StrokeColor)
+	}
+	fillMask(imgMatrix, mask, box.Min.X, box.Min.Y, style.Color)
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: imgMatrix,
+		img: il.GetImg(),
+	}
+}
+
+// 按宽度 width 贪心换行
+func wrapText(face font.Face, text string, width int) []string {
+	limit := fixed.I(width)
+	var lines []string
+
+	for _, paragraph := range strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n") {
+		var line string
+		for _, token := range splitTokens(paragraph) {
+			if line != "" && font.MeasureString(face, line+token) > limit {
+				lines = append(lines, strings.TrimRight(line, " "))
+				line = ""
+				token = strings.TrimLeft(token, " ")
+			}
+			line += token
+
+			// 单个词比整行还宽时按字符断开
+			for font.MeasureString(face, line) > limit && len([]rune(line)) > 1 {
+				runes := []rune(line)
+				n := len(runes) - 1
+				for n > 1 && font.MeasureString(face, string(runes[:n])) > limit {
+					n--
+				}
+				lines = append(lines, string(runes[:n]))
+				line = string(runes[n:])
+			}
+		}
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+
+	return lines
+}
+
+// 拆分为可断行的片段：连续的空白、连续的非空白西文字符各为一段，中日韩文字每个字为一段
+func splitTokens(s string) []string {
+	var tokens []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			tokens = append(tokens, string(current))
+			current = current[:0]
+		}
+	}
+
+	for _, r := range s {
+		switch {
+		case isCJK(r):
+			flush()
+			tokens = append(tokens, string(r))
+		case len(current) > 0 && unicode.IsSpace(current[0]) != unicode.IsSpace(r):
+			flush()
+			current = append(current, r)
+		default:
+			current = append(current, r)
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r) || (r >= 0x3000 && r <= 0x303f) || (r >= 0xff00 && r <= 0xffef)
+}
+
+// 把各行文字绘制到 width x height 的覆盖率蒙版上
+func textMask(face font.Face, lines []string, width, height int, align TextAlign, lineSpacing float64) *image.Alpha {
+	if lineSpacing <= 0 {
+		lineSpacing = 1
+	}
+	mask := image.NewAlpha(image.Rect(0, 0, width, height))
+	metrics := face.Metrics()
+	lineHeight := fixed.Int26_6(float64(metrics.Height) * lineSpacing)
+
+	drawer := &font.Drawer{Dst: mask, Src: image.Opaque, Face: face}
+	baseline := metrics.Ascent
+	for _, line := range lines {
+		if (baseline - metrics.Ascent).Ceil() >= height {
+			break
+		}
+
+		x := fixed.I(0)
+		switch align {
+		case AlignCenter:
+			x = (fixed.I(width) - font.MeasureString(face, line)) / 2
+		case AlignRight:
+			x = fixed.I(width) - font.MeasureString(face, line)
+		}
+		drawer.Dot = fixed.Point26_6{X: x, Y: baseline}
+		drawer.DrawString(line)
+		baseline += lineHeight
+	}
+
+	return mask
+}
+
+// 最近邻整数倍放大
+func scaleMask(mask *image.Alpha, scale int) *image.Alpha {
+	bounds := mask.Bounds()
+	dst := image.NewAlpha(image.Rect(0, 0, bounds.Dx()*scale, bounds.Dy()*scale))
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			dst.Pix[y*dst.Stride+x] = mask.Pix[(y/scale)*mask.Stride+x/scale]
+		}
+	}
+
+	return dst
+}
+
+// 以半径 r 的圆盘做膨胀，得到描边的蒙版
+func dilateMask(mask *image.Alpha, r int) *image.Alpha {
+	bounds := mask.Bounds()
+	dst := image.NewAlpha(image.Rect(bounds.Min.X-r, bounds.Min.Y-r, bounds.Max.X+r, bounds.Max.Y+r))
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			a := mask.AlphaAt(x, y).A
+			if a == 0 {
+				continue
+			}
+			for dy := -r; dy <= r; dy++ {
+				for dx := -r; dx <= r; dx++ {
+					// 圆盘边缘按距离衰减，使描边保持抗锯齿
+					cover := math.Min(1, float64(r)+0.5-math.Hypot(float64(dx), float64(dy)))
+					if cover <= 0 {
+						continue
+					}
+					v := uint8(float64(a) * cover)
+					if v > dst.AlphaAt(x+dx, y+dy).A {
+						dst.SetAlpha(x+dx, y+dy, color.Alpha{A: v})
+					}
+				}
+			}
+		}
+	}
+
+	return dst
+}
+
+// 以蒙版为覆盖率，把颜色 c 合成到矩阵中，蒙版原点对齐 (x, y)
+func fillMask(matrix [][][]uint8, mask *image.Alpha, x, y int, c color.RGBA) {
+	bounds := mask.Bounds()
+	src := []uint8{c.R, c.G, c.B, 0}
+	for my := bounds.Min.Y; my < bounds.Max.Y; my++ {
+		hi := y + my
+		if hi < 0 || hi >= len(matrix) {
+			continue
+		}
+		for mx := bounds.Min.X; mx < bounds.Max.X; mx++ {
+			wi := x + mx
+			a := mask.AlphaAt(mx, my).A
+			if a == 0 || wi < 0 || wi >= len(matrix[hi]) {
+				continue
+			}
+			src[3] = uint8((int(a)*int(c.A) + 127) / 255)
+			blendPixel(matrix[hi][wi], src, BlendNormal, 1)
+		}
+	}
+}
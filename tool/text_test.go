@@ -0,0 +1,89 @@
+package tool
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+)
+
+// 返回与底色不同的像素所占的行数
+func inkRows(il *ImgLoader) int {
+	rows := 0
+	for _, row := range il.GetMatrix() {
+		for _, p := range row {
+			if p[0] != 0 {
+				rows++
+				break
+			}
+		}
+	}
+
+	return rows
+}
+
+func blankImage(width, height int) *ImgLoader {
+	matrix := NewRGBAMatrix(height, width)
+	for hi := range matrix {
+		for wi := range matrix[hi] {
+			matrix[hi][wi][3] = 255
+		}
+	}
+
+	return &ImgLoader{filename: "blank", matrix: matrix}
+}
+
+func TestDrawTextTrueTypeSize(t *testing.T) {
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	style := DefaultTextStyle()
+	style.Font = f
+	style.Color = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+
+	ip := &ImgProcessor{}
+	style.Size = 20
+	small := inkRows(ip.DrawText(blankImage(300, 200), "H", image.Rectangle{}, style))
+	style.Size = 60
+	large := inkRows(ip.DrawText(blankImage(300, 200), "H", image.Rectangle{}, style))
+
+	// 大写字母高度约为字号的 0.7 倍
+	if small < 10 || small > 20 || large < 3*small-6 || large > 3*small+6 {
+		t.Errorf("glyph heights %d and %d px at sizes 20 and 60", small, large)
+	}
+}
+
+func TestDrawTextBox(t *testing.T) {
+	ip := &ImgProcessor{}
+	style := DefaultTextStyle()
+	style.Size = 13
+
+	if rows := inkRows(ip.DrawText(blankImage(60, 40), "Hi", image.Rectangle{}, style)); rows == 0 {
+		t.Error("zero box: nothing drawn")
+	}
+
+	// 与图片不相交的 box 不应退回整幅图片
+	for _, box := range []image.Rectangle{image.Rect(100, 100, 200, 150), image.Rect(-50, 0, -10, 40), image.Rect(10, 10, 10, 30)} {
+		if rows := inkRows(ip.DrawText(blankImage(60, 40), "Hi", box, style)); rows != 0 {
+			t.Errorf("box %v: %d rows drawn, want none", box, rows)
+		}
+	}
+
+	// 部分在图片外的 box 只在相交部分绘制
+	result := ip.DrawText(blankImage(60, 40), "Hi", image.Rect(30, 20, 100, 100), style)
+	matrix := result.GetMatrix()
+	for hi := range matrix {
+		for wi := range matrix[hi] {
+			if (hi < 20 || wi < 30) && matrix[hi][wi][0] != 0 {
+				t.Fatalf("pixel (%d, %d) outside the box was drawn", hi, wi)
+			}
+		}
+	}
+	if inkRows(result) == 0 {
+		t.Error("partially visible box: nothing drawn")
+	}
+}
@@ -0,0 +1,200 @@
+package tool
+
+import "math"
+
+type ThresholdMethod int
+
+const (
+	ThresholdFixed ThresholdMethod = iota
+	ThresholdOtsu
+	ThresholdTriangle
+)
+
+type AdaptiveMethod int
+
+const (
+	AdaptiveMean AdaptiveMethod = iota
+	AdaptiveGaussian
+	AdaptiveSauvola
+	AdaptiveNiblack
+)
+
+// Sauvola 公式中标准差的动态范围
+const sauvolaR = 128
+
+// 全局二值化，亮度大于阈值的像素为白色，其余为黑色。
+// ThresholdFixed 使用传入的 t，其他方法忽略 t 并自动计算，返回实际使用的阈值
+func (ip *ImgProcessor)Binarize(il *ImgLoader, method ThresholdMethod, t uint8) (*ImgLoader, uint8) {
+	gray := grayPlane(il.GetMatrix())
+
+	switch method {
+	case ThresholdOtsu:
+		t = otsuThreshold(grayHistogram(gray))
+	case ThresholdTriangle:
+		t = triangleThreshold(grayHistogram(gray))
+	}
+
+	mask := NewRGBAMatrix(il.GetMY(), il.GetMX())
+	for hi := range mask {
+		for wi := range mask[hi] {
+			setMask(mask[hi][wi], clampUint8(gray[hi][wi]) > t)
+		}
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: mask,
+		img: il.GetImg(),
+	}, t
+}
+
+// 计算 Otsu 阈值（类间方差最大）
+func (ip *ImgProcessor)OtsuThreshold(il *ImgLoader) uint8 {
+	return otsuThreshold(grayHistogram(grayPlane(il.GetMatrix())))
+}
+
+// 计算三角法阈值，适合直方图只有一个明显峰值的图片
+func (ip *ImgProcessor)TriangleThreshold(il *ImgLoader) uint8 {
+	return triangleThreshold(grayHistogram(grayPlane(il.GetMatrix())))
+}
+
+// 局部自适应二值化，blockSize 为窗口边长（会被修正为不小于 3 的奇数）。
+// k 的含义随方法变化：
+//   AdaptiveMean / AdaptiveGaussian: 从局部均值中减去的常数 C，常用 5 ~ 15
+//   AdaptiveSauvola: 灵敏度，常用 0.2 ~ 0.5
+//   AdaptiveNiblack: 标准差系数，常用 -0.2
+func (ip *ImgProcessor)AdaptiveBinarize(il *ImgLoader, method AdaptiveMethod, blockSize int, k float64) *ImgLoader {
+	if blockSize < 3 {
+		blockSize = 3
+	}
+	if blockSize%2 == 0 {
+		blockSize++
+	}
+	r := blockSize / 2
+
+	gray := grayPlane(il.GetMatrix())
+	in := newIntegral(gray)
+
+	var blurred plane
+	if method == AdaptiveGaussian {
+		// 与 OpenCV 相同的由窗口大小推导 sigma 的方式
+		blurred = gray.gaussianBlur(0.3*(float64(r)-1) + 0.8)
+	}
+
+	mask := NewRGBAMatrix(il.GetMY(), il.GetMX())
+	for hi := range mask {
+		for wi := range mask[hi] {
+			var t float64
+			switch method {
+			case AdaptiveGaussian:
+				t = blurred[hi][wi] - k
+			case AdaptiveSauvola:
+				mean, std := in.meanStd(hi, wi, r)
+				t = mean * (1 + k*(std/sauvolaR-1))
+			case AdaptiveNiblack:
+				mean, std := in.meanStd(hi, wi, r)
+				t = mean + k*std
+			default:
+				mean, _ := in.meanStd(hi, wi, r)
+				t = mean - k
+			}
+			setMask(mask[hi][wi], gray[hi][wi] > t)
+		}
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: mask,
+		img: il.GetImg(),
+	}
+}
+
+func setMask(pixel []uint8, on bool) {
+	var v uint8
+	if on {
+		v = math.MaxUint8
+	}
+	pixel[0], pixel[1], pixel[2], pixel[3] = v, v, v, math.MaxUint8
+}
+
+func grayHistogram(gray plane) (hist [256]int) {
+	for hi := range gray {
+		for wi := range gray[hi] {
+			hist[clampUint8(gray[hi][wi])]++
+		}
+	}
+
+	return
+}
+
+func otsuThreshold(hist [256]int) uint8 {
+	var total, sum float64
+	for i, n := range hist {
+		total += float64(n)
+		sum += float64(i * n)
+	}
+
+	var bestT int
+	var wB, sumB, best float64
+	for t, n := range hist {
+		wB += float64(n)
+		if wB == 0 {
+			continue
+		}
+		wF := total - wB
+		if wF == 0 {
+			break
+		}
+		sumB += float64(t * n)
+		mB := sumB / wB
+		mF := (sum - sumB) / wF
+		between := wB * wF * (mB - mF) * (mB - mF)
+		if between > best {
+			best = between
+			bestT = t
+		}
+	}
+
+	return uint8(bestT)
+}
+
+func triangleThreshold(hist [256]int) uint8 {
+	left, right, peak := 0, 255, 0
+	for left < 255 && hist[left] == 0 {
+		left++
+	}
+	for right > 0 && hist[right] == 0 {
+		right--
+	}
+	for i := range hist {
+		if hist[i] > hist[peak] {
+			peak = i
+		}
+	}
+
+	// 在峰值与较长一侧的尾端之间连线，取直线下方距离最大的灰度级
+	end := clampInt(right+1, 0, 255)
+	if peak-left > right-peak {
+		end = clampInt(left-1, 0, 255)
+	}
+	if end == peak {
+		return uint8(peak)
+	}
+
+	best, bestT := -1.0, peak
+	lo, hi := peak, end
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	for i := lo; i <= hi; i++ {
+		line := float64(hist[peak]) * float64(end-i) / float64(end-peak)
+		if gap := line - float64(hist[i]); gap > best {
+			best = gap
+			bestT = i
+		}
+	}
+
+	return uint8(bestT)
+}
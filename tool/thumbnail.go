@@ -0,0 +1,118 @@
+package tool
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+)
+
+type ThumbnailMode int
+
+const (
+	ThumbFit   ThumbnailMode = iota // 等比缩放到框内，输出尺寸可能小于框
+	ThumbFill                       // 等比缩放铺满框，居中裁掉多余部分
+	ThumbPad                        // 等比缩放到框内，空白处用背景色补齐
+	ThumbSmart                      // 与 ThumbFill 相同，但裁剪位置选在细节最丰富的区域
+)
+
+var thumbnailModeNames = []string{"fit", "fill", "pad", "smart"}
+
+func (mode ThumbnailMode)String() string {
+	if mode < ThumbFit || mode > ThumbSmart {
+		return "unknown"
+	}
+
+	return thumbnailModeNames[mode]
+}
+
+func ParseThumbnailMode(name string) (ThumbnailMode, error) {
+	for i, v := range thumbnailModeNames {
+		if strings.ToLower(name) == v {
+			return ThumbnailMode(i), nil
+		}
+	}
+
+	return ThumbFit, fmt.Errorf("unknown thumbnail mode: %s", name)
+}
+
+// 生成 width x height 框内的缩略图，bg 只在 ThumbPad 模式下使用
+func (ip *ImgProcessor)Thumbnail(il *ImgLoader, width, height int, mode ThumbnailMode, bg color.RGBA) *ImgLoader {
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	srcWidth, srcHeight := float64(il.GetMX()), float64(il.GetMY())
+
+	switch mode {
+	case ThumbFill, ThumbSmart:
+		// 先在原图上裁出与框同比例的最大区域，再缩放。宽高比相差悬殊时至少保留 1 个像素
+		cropWidth := clampInt(int(math.Round(srcHeight*float64(width)/float64(height))), 1, il.GetMX())
+		cropHeight := clampInt(int(math.Round(srcWidth*float64(height)/float64(width))), 1, il.GetMY())
+		x, y := (il.GetMX()-cropWidth)/2, (il.GetMY()-cropHeight)/2
+		if mode == ThumbSmart {
+			x, y = salientWindow(il.GetMatrix(), cropWidth, cropHeight)
+		}
+
+		cropped := &ImgLoader{
+			filename: il.GetFileName(),
+			format: il.GetFormat(),
+			matrix: cropMatrix(il.GetMatrix(), x, y, cropWidth, cropHeight),
+			img: il.GetImg(),
+		}
+		return ip.ResizeWith(cropped, height, width, Lanczos3)
+	}
+
+	scale := math.Min(float64(width)/srcWidth, float64(height)/srcHeight)
+	fitWidth := clampInt(int(math.Round(srcWidth*scale)), 1, width)
+	fitHeight := clampInt(int(math.Round(srcHeight*scale)), 1, height)
+	fitted := ip.ResizeWith(il, fitHeight, fitWidth, Lanczos3)
+	if mode != ThumbPad {
+		return fitted
+	}
+
+	return ip.ResizeCanvas(fitted, width, height, AnchorCenter, bg)
+}
+
+// 复制 matrix 中左上角为 (x, y)、大小为 w x h 的区域
+func cropMatrix(matrix [][][]uint8, x, y, w, h int) [][][]uint8 {
+	cropped := NewRGBAMatrix(h, w)
+	for hi := range cropped {
+		for wi := range cropped[hi] {
+			copy(cropped[hi][wi], matrix[y+hi][x+wi])
+		}
+	}
+
+	return cropped
+}
+
+// 在 matrix 中寻找显著性之和最大的 w x h 窗口，返回其左上角。
+// 显著性取亮度梯度幅值与饱和度之和，梯度反映细节，饱和度让鲜艳的主体更容易被选中
+func salientWindow(matrix [][][]uint8, w, h int) (x, y int) {
+	height, width := len(matrix), len(matrix[0])
+	saliency := grayPlane(matrix).gradientMagnitude()
+	for hi := range saliency {
+		for wi := range saliency[hi] {
+			p := matrix[hi][wi]
+			max := math.Max(float64(p[0]), math.Max(float64(p[1]), float64(p[2])))
+			min := math.Min(float64(p[0]), math.Min(float64(p[1]), float64(p[2])))
+			saliency[hi][wi] += max - min
+		}
+	}
+	in := newIntegral(saliency)
+
+	best := -1.0
+	for top := 0; top+h <= height; top++ {
+		for left := 0; left+w <= width; left++ {
+			sum := in.sum[top+h][left+w] - in.sum[top][left+w] - in.sum[top+h][left] + in.sum[top][left]
+			if sum > best {
+				best = sum
+				x, y = left, top
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,33 @@
+package tool
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestThumbnailExtremeAspectRatio(t *testing.T) {
+	ip := &ImgProcessor{}
+	cases := []struct {
+		srcW, srcH, w, h int
+	}{
+		{1000, 10, 10, 1000},
+		{10, 1000, 1000, 10},
+		{1, 1, 50, 20},
+	}
+
+	for _, c := range cases {
+		il := &ImgLoader{matrix: NewRGBAMatrix(c.srcH, c.srcW)}
+		for _, mode := range []ThumbnailMode{ThumbFit, ThumbFill, ThumbPad, ThumbSmart} {
+			result := ip.Thumbnail(il, c.w, c.h, mode, color.RGBA{})
+			if mode == ThumbFit {
+				if result.GetMX() > c.w || result.GetMY() > c.h {
+					t.Errorf("%dx%d -> %dx%d %s: got %dx%d", c.srcW, c.srcH, c.w, c.h, mode, result.GetMX(), result.GetMY())
+				}
+				continue
+			}
+			if result.GetMX() != c.w || result.GetMY() != c.h {
+				t.Errorf("%dx%d -> %dx%d %s: got %dx%d", c.srcW, c.srcH, c.w, c.h, mode, result.GetMX(), result.GetMY())
+			}
+		}
+	}
+}
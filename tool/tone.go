@@ -0,0 +1,116 @@
+package tool
+
+import "math"
+
+// 亮度、对比度、伽马、曝光等色调调整。
+// 所有调整都只作用于 RGB 三个通道，透明度保持不变
+
+// 调整亮度：每个通道加上 offset，范围 [-255, 255]
+func (ip *ImgProcessor)AdjustBrightness(il *ImgLoader, offset float64) *ImgLoader {
+	offset = math.Max(-255, math.Min(255, offset))
+
+	var lut [256]uint8
+	for i := range lut {
+		lut[i] = clampUint8(float64(i) + offset)
+	}
+
+	return applyLUT(il, [3][256]uint8{lut, lut, lut})
+}
+
+// 调整对比度：以 pivot 为中心拉伸（factor > 1）或压缩（factor < 1）。
+// factor 范围 [0, 4]，为 0 时图片变为 pivot 对应的纯灰；pivot 范围 [0, 255]，通常取 128
+func (ip *ImgProcessor)AdjustContrast(il *ImgLoader, factor, pivot float64) *ImgLoader {
+	factor = math.Max(0, math.Min(4, factor))
+	pivot = math.Max(0, math.Min(255, pivot))
+
+	var lut [256]uint8
+	for i := range lut {
+		lut[i] = clampUint8((float64(i)-pivot)*factor + pivot)
+	}
+
+	return applyLUT(il, [3][256]uint8{lut, lut, lut})
+}
+
+// 伽马校正：out = 255 * (in / 255) ^ (1 / gamma)。
+// gamma 范围 [0.1, 10]，大于 1 时提亮暗部，小于 1 时压暗
+func (ip *ImgProcessor)AdjustGamma(il *ImgLoader, gamma float64) *ImgLoader {
+	gamma = math.Max(0.1, math.Min(10, gamma))
+
+	var lut [256]uint8
+	for i := range lut {
+		lut[i] = clampUint8(255 * math.Pow(float64(i)/255, 1/gamma))
+	}
+
+	return applyLUT(il, [3][256]uint8{lut, lut, lut})
+}
+
+// 调整曝光：在线性光空间中乘以 2^stops，模拟相机增减档位。stops 范围 [-10, 10]
+func (ip *ImgProcessor)AdjustExposure(il *ImgLoader, stops float64) *ImgLoader {
+	stops = math.Max(-10, math.Min(10, stops))
+	gain := math.Pow(2, stops)
+
+	var lut [256]uint8
+	for i := range lut {
+		linear := SRGBToLinear(float64(i)/255) * gain
+		lut[i] = clampUint8(LinearToSRGB(math.Min(linear, 1)) * 255)
+	}
+
+	return applyLUT(il, [3][256]uint8{lut, lut, lut})
+}
+
+// 自动色阶：按亮度直方图两端各裁掉 clip 百分比的像素，把剩余范围拉伸到 [0, 255]。
+// 三个通道使用同一映射，因此不会偏色。clip 范围 [0, 10]
+func (ip *ImgProcessor)AutoLevels(il *ImgLoader, clip float64) *ImgLoader {
+	clip = math.Max(0, math.Min(10, clip)) / 100
+
+	c := cdf(ip.GetHistogram(il).Luma)
+	low, high := 0, 255
+	for low < 255 && c[low] <= clip {
+		low++
+	}
+	for high > 0 && c[high-1] >= 1-clip {
+		high--
+	}
+	if high <= low {
+		return applyLUT(il, identityLUTs())
+	}
+
+	var lut [256]uint8
+	for i := range lut {
+		lut[i] = clampUint8(float64(i-low) / float64(high-low) * 255)
+	}
+
+	return applyLUT(il, [3][256]uint8{lut, lut, lut})
+}
+
+// 按查找表逐通道映射 RGB，luts 依次对应 R、G、B
+func applyLUT(il *ImgLoader, luts [3][256]uint8) *ImgLoader {
+	src := il.GetMatrix()
+	imgMatrix := NewRGBAMatrix(il.GetMY(), il.GetMX())
+
+	for hi := range imgMatrix {
+		for wi := range imgMatrix[hi] {
+			for c := 0; c < 3; c++ {
+				imgMatrix[hi][wi][c] = luts[c][src[hi][wi][c]]
+			}
+			imgMatrix[hi][wi][3] = src[hi][wi][3]
+		}
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: imgMatrix,
+		img: il.GetImg(),
+	}
+}
+
+func identityLUTs() (luts [3][256]uint8) {
+	for c := range luts {
+		for i := range luts[c] {
+			luts[c][i] = uint8(i)
+		}
+	}
+
+	return
+}
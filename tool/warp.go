@@ -0,0 +1,212 @@
+package tool
+
+import (
+	"errors"
+	"image/color"
+	"math"
+)
+
+// 平面上的点，坐标以像素中心为整数点
+type Point struct {
+	X float64
+	Y float64
+}
+
+// 2x3 仿射矩阵 [a b c; d e f]，把 (x, y) 映射为 (a*x + b*y + c, d*x + e*y + f)
+type Affine [6]float64
+
+func IdentityAffine() Affine {
+	return Affine{1, 0, 0, 0, 1, 0}
+}
+
+func ScaleAffine(sx, sy float64) Affine {
+	return Affine{sx, 0, 0, 0, sy, 0}
+}
+
+// 错切，x' = x + shx*y，y' = y + shy*x
+func ShearAffine(shx, shy float64) Affine {
+	return Affine{1, shx, 0, shy, 1, 0}
+}
+
+// 绕原点旋转，degrees 为正时在图像坐标系（y 轴向下）中顺时针
+func RotateAffine(degrees float64) Affine {
+	rad := degrees * math.Pi / 180
+	sin, cos := math.Sin(rad), math.Cos(rad)
+	return Affine{cos, -sin, 0, sin, cos, 0}
+}
+
+func TranslateAffine(tx, ty float64) Affine {
+	return Affine{1, 0, tx, 0, 1, ty}
+}
+
+// 复合变换 m * n，即先做 n 再做 m
+func (m Affine)Multiply(n Affine) Affine {
+	return Affine{
+		m[0]*n[0] + m[1]*n[3], m[0]*n[1] + m[1]*n[4], m[0]*n[2] + m[1]*n[5] + m[2],
+		m[3]*n[0] + m[4]*n[3], m[3]*n[1] + m[4]*n[4], m[3]*n[2] + m[4]*n[5] + m[5],
+	}
+}
+
+func (m Affine)Invert() (Affine, error) {
+	det := m[0]*m[4] - m[1]*m[3]
+	if math.Abs(det) < 1e-12 {
+		return Affine{}, errors.New("affine matrix is not invertible")
+	}
+
+	a, b, d, e := m[4]/det, -m[1]/det, -m[3]/det, m[0]/det
+	return Affine{a, b, -(a*m[2] + b*m[5]), d, e, -(d*m[2] + e*m[5])}, nil
+}
+
+func (m Affine)Apply(p Point) Point {
+	return Point{X: m[0]*p.X + m[1]*p.Y + m[2], Y: m[3]*p.X + m[4]*p.Y + m[5]}
+}
+
+// 对图片做仿射变换，输出大小为 width x height。
+// width 或 height 不大于 0 时，输出恰好容纳变换后的整幅图（会自动平移到画布内）
+func (ip *ImgProcessor)WarpAffine(il *ImgLoader, m Affine, width, height int, interp Interpolation, bg color.RGBA) (*ImgLoader, error) {
+	if width <= 0 || height <= 0 {
+		var bounds [4]Point
+		for i, p := range imageCorners(il) {
+			bounds[i] = m.Apply(p)
+		}
+		var minX, minY float64
+		minX, minY, width, height = boundingBox(bounds[:])
+		m = TranslateAffine(-minX, -minY).Multiply(m)
+	}
+
+	inv, err := m.Invert()
+	if err != nil {
+		return nil, err
+	}
+
+	return warp(il, width, height, interp, bg, inv.Apply), nil
+}
+
+// 3x3 单应矩阵，按行存放，作用于齐次坐标 (x, y, 1)
+type Homography [9]float64
+
+// 由四对对应点 src[i] -> dst[i] 计算单应矩阵（h33 固定为 1，求解 8 元线性方程组）
+func ComputeHomography(src, dst [4]Point) (Homography, error) {
+	var a [8][9]float64
+	for i := 0; i < 4; i++ {
+		x, y, u, v := src[i].X, src[i].Y, dst[i].X, dst[i].Y
+		a[2*i] = [9]float64{x, y, 1, 0, 0, 0, -u * x, -u * y, u}
+		a[2*i+1] = [9]float64{0, 0, 0, x, y, 1, -v * x, -v * y, v}
+	}
+
+	// 列主元高斯消元
+	for col := 0; col < 8; col++ {
+		pivot := col
+		for row := col + 1; row < 8; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return Homography{}, errors.New("degenerate point correspondences")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+
+		for row := 0; row < 8; row++ {
+			if row == col {
+				continue
+			}
+			f := a[row][col] / a[col][col]
+			for k := col; k < 9; k++ {
+				a[row][k] -= f * a[col][k]
+			}
+		}
+	}
+
+	var h Homography
+	for i := 0; i < 8; i++ {
+		h[i] = a[i][8] / a[i][i]
+	}
+	h[8] = 1
+
+	return h, nil
+}
+
+func (h Homography)Invert() (Homography, error) {
+	det := h[0]*(h[4]*h[8]-h[5]*h[7]) - h[1]*(h[3]*h[8]-h[5]*h[6]) + h[2]*(h[3]*h[7]-h[4]*h[6])
+	if math.Abs(det) < 1e-12 {
+		return Homography{}, errors.New("homography is not invertible")
+	}
+
+	return Homography{
+		(h[4]*h[8] - h[5]*h[7]) / det, (h[2]*h[7] - h[1]*h[8]) / det, (h[1]*h[5] - h[2]*h[4]) / det,
+		(h[5]*h[6] - h[3]*h[8]) / det, (h[0]*h[8] - h[2]*h[6]) / det, (h[2]*h[3] - h[0]*h[5]) / det,
+		(h[3]*h[7] - h[4]*h[6]) / det, (h[1]*h[6] - h[0]*h[7]) / det, (h[0]*h[4] - h[1]*h[3]) / det,
+	}, nil
+}
+
+func (h Homography)Apply(p Point) Point {
+	w := h[6]*p.X + h[7]*p.Y + h[8]
+	if w == 0 {
+		return Point{X: math.Inf(1), Y: math.Inf(1)}
+	}
+
+	return Point{X: (h[0]*p.X + h[1]*p.Y + h[2]) / w, Y: (h[3]*p.X + h[4]*p.Y + h[5]) / w}
+}
+
+// 对图片做透视变换，输出大小为 width x height
+func (ip *ImgProcessor)WarpPerspective(il *ImgLoader, h Homography, width, height int, interp Interpolation, bg color.RGBA) (*ImgLoader, error) {
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("output size has to be greater than 0")
+	}
+
+	inv, err := h.Invert()
+	if err != nil {
+		return nil, err
+	}
+
+	return warp(il, width, height, interp, bg, inv.Apply), nil
+}
+
+// 把原图中的四边形 quad（依次为左上、右上、右下、左下）矫正为 width x height 的矩形，常用于文档扫描
+func (ip *ImgProcessor)Deskew(il *ImgLoader, quad [4]Point, width, height int, interp Interpolation, bg color.RGBA) (*ImgLoader, error) {
+	rect := [4]Point{{0, 0}, {float64(width - 1), 0}, {float64(width - 1), float64(height - 1)}, {0, float64(height - 1)}}
+	h, err := ComputeHomography(quad, rect)
+	if err != nil {
+		return nil, err
+	}
+
+	return ip.WarpPerspective(il, h, width, height, interp, bg)
+}
+
+// 逆向映射：对输出的每个像素用 inverse 求出原图坐标后采样
+func warp(il *ImgLoader, width, height int, interp Interpolation, bg color.RGBA, inverse func(Point) Point) *ImgLoader {
+	src := il.GetMatrix()
+	imgMatrix := NewRGBAMatrix(height, width)
+
+	for hi := range imgMatrix {
+		for wi := range imgMatrix[hi] {
+			p := inverse(Point{X: float64(wi), Y: float64(hi)})
+			setPixel(imgMatrix[hi][wi], sample(src, p.X, p.Y, interp, bg))
+		}
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: imgMatrix,
+		img: il.GetImg(),
+	}
+}
+
+func imageCorners(il *ImgLoader) [4]Point {
+	w, h := float64(il.GetMX()-1), float64(il.GetMY()-1)
+	return [4]Point{{0, 0}, {w, 0}, {w, h}, {0, h}}
+}
+
+// 点集的包围盒，返回左上角与像素尺寸
+func boundingBox(points []Point) (minX, minY float64, width, height int) {
+	minX, minY = math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range points {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+
+	return minX, minY, int(math.Ceil(maxX-minX)) + 1, int(math.Ceil(maxY-minY)) + 1
+}
@@ -0,0 +1,130 @@
+package tool
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func pointsClose(a, b Point, eps float64) bool {
+	return math.Abs(a.X-b.X) < eps && math.Abs(a.Y-b.Y) < eps
+}
+
+// 3x3 矩阵乘积 h * g
+func multiplyHomography(h, g Homography) Homography {
+	var r Homography
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				r[3*i+j] += h[3*i+k] * g[3*k+j]
+			}
+		}
+	}
+
+	return r
+}
+
+func TestAffineInvert(t *testing.T) {
+	m := TranslateAffine(12, -7).Multiply(RotateAffine(30)).Multiply(ScaleAffine(2, 0.5)).Multiply(ShearAffine(0.3, 0))
+	inv, err := m.Invert()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identity := IdentityAffine()
+	for _, product := range []Affine{m.Multiply(inv), inv.Multiply(m)} {
+		for i := range product {
+			if math.Abs(product[i]-identity[i]) > 1e-9 {
+				t.Fatalf("m * inv = %v, want identity", product)
+			}
+		}
+	}
+
+	p := Point{X: 3, Y: 11}
+	if q := inv.Apply(m.Apply(p)); !pointsClose(p, q, 1e-9) {
+		t.Errorf("round trip of %v gave %v", p, q)
+	}
+
+	if _, err = ScaleAffine(2, 0).Invert(); err == nil {
+		t.Error("expected an error for a singular matrix")
+	}
+}
+
+func TestComputeHomography(t *testing.T) {
+	want := Homography{1.2, 0.1, 5, -0.2, 0.9, 3, 0.001, 0.002, 1}
+	src := [4]Point{{0, 0}, {100, 0}, {100, 80}, {0, 80}}
+	var dst [4]Point
+	for i, p := range src {
+		dst[i] = want.Apply(p)
+	}
+
+	h, err := ComputeHomography(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range h {
+		if math.Abs(h[i]-want[i]) > 1e-9 {
+			t.Fatalf("h = %v, want %v", h, want)
+		}
+	}
+
+	inv, err := h.Invert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range src {
+		if q := h.Apply(p); !pointsClose(q, dst[i], 1e-9) {
+			t.Errorf("h maps %v to %v, want %v", p, q, dst[i])
+		}
+		if q := inv.Apply(dst[i]); !pointsClose(q, p, 1e-9) {
+			t.Errorf("inverse maps %v to %v, want %v", dst[i], q, p)
+		}
+	}
+
+	// 四边形内部的点同样能往返
+	p := Point{X: 37, Y: 52}
+	if q := inv.Apply(h.Apply(p)); !pointsClose(q, p, 1e-9) {
+		t.Errorf("round trip of %v gave %v", p, q)
+	}
+}
+
+func TestHomographyInvert(t *testing.T) {
+	h := Homography{0.8, -0.3, 40, 0.25, 1.1, -12, 0.0005, -0.001, 1}
+	inv, err := h.Invert()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, product := range []Homography{multiplyHomography(h, inv), multiplyHomography(inv, h)} {
+		for i := range product {
+			want := 0.0
+			if i%4 == 0 {
+				want = 1
+			}
+			if math.Abs(product[i]-want) > 1e-9 {
+				t.Fatalf("h * inv = %v, want identity", product)
+			}
+		}
+	}
+
+	if _, err = (Homography{1, 2, 3, 2, 4, 6, 0, 0, 1}).Invert(); err == nil {
+		t.Error("expected an error for a singular matrix")
+	}
+}
+
+func TestComputeHomographyDegenerate(t *testing.T) {
+	rect := [4]Point{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	collinear := [4]Point{{0, 0}, {1, 1}, {2, 2}, {3, 3}}
+
+	if _, err := ComputeHomography(collinear, rect); err == nil {
+		t.Error("expected an error for a collinear source quad")
+	}
+	if _, err := ComputeHomography(rect, collinear); err == nil {
+		t.Error("expected an error for a collinear destination quad")
+	}
+
+	il := &ImgLoader{matrix: NewRGBAMatrix(4, 4)}
+	if _, err := new(ImgProcessor).Deskew(il, collinear, 10, 10, InterpBilinear, color.RGBA{}); err == nil {
+		t.Error("Deskew: expected an error for a collinear quad")
+	}
+}
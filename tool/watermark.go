@@ -0,0 +1,143 @@
+package tool
+
+import (
+	"fmt"
+	"image/color"
+	"io/ioutil"
+	"math"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// 水印的重复方式
+type TileMode int
+
+const (
+	TileNone     TileMode = iota // 只在锚点处放一个
+	TileGrid                     // 网格平铺
+	TileDiagonal                 // 旋转 30 度后错行平铺
+)
+
+var tileModeNames = []string{"none", "grid", "diagonal"}
+
+func (mode TileMode)String() string {
+	if mode < TileNone || mode > TileDiagonal {
+		return "unknown"
+	}
+
+	return tileModeNames[mode]
+}
+
+func ParseTileMode(name string) (TileMode, error) {
+	for i, v := range tileModeNames {
+		if strings.ToLower(name) == v {
+			return TileMode(i), nil
+		}
+	}
+
+	return TileNone, fmt.Errorf("unknown tile mode: %s", name)
+}
+
+func TileModeNames() []string {
+	return tileModeNames
+}
+
+type WatermarkOptions struct {
+	Anchor  Anchor
+	OffsetX int     // 在锚点位置上的额外偏移，向右为正
+	OffsetY int     // 向下为正
+	Margin  int     // 与图片边缘的距离，平铺时为水印之间的间距
+	Scale   float64 // 水印宽度占原图宽度的比例，不大于 0 时保持水印原尺寸
+	Opacity float64 // [0, 1]
+	Tile    TileMode
+}
+
+func DefaultWatermarkOptions() WatermarkOptions {
+	return WatermarkOptions{Anchor: AnchorBottomRight, Margin: 16, Scale: 0.2, Opacity: 0.6}
+}
+
+// 把 mark 按 opt 摆放并合成到 il 上，保持 mark 的宽高比
+func (ip *ImgProcessor)Watermark(il, mark *ImgLoader, opt WatermarkOptions) *ImgLoader {
+	width, height := il.GetMX(), il.GetMY()
+
+	if opt.Scale > 0 {
+		w := int(math.Max(1, math.Round(float64(width)*opt.Scale)))
+		h := int(math.Max(1, math.Round(float64(w)*float64(mark.GetMY())/float64(mark.GetMX()))))
+		mark = ip.ResizeWith(mark, h, w, CatmullRom)
+	}
+	if opt.Tile == TileDiagonal {
+		mark = ip.RotateAngle(mark, -30, InterpBilinear, color.RGBA{}, true)
+	}
+	markMatrix := mark.GetMatrix()
+	markWidth, markHeight := mark.GetMX(), mark.GetMY()
+
+	imgMatrix := il.GetMatrix()
+	if opt.Tile == TileNone {
+		x, y := opt.Anchor.offset(width-2*opt.Margin, height-2*opt.Margin, markWidth, markHeight)
+		blendMatrix(imgMatrix, markMatrix, x+opt.Margin+opt.OffsetX, y+opt.Margin+opt.OffsetY, BlendNormal, opt.Opacity)
+	} else {
+		stepX, stepY := markWidth+opt.Margin, markHeight+opt.Margin
+		if stepX < 1 {
+			stepX = 1
+		}
+		if stepY < 1 {
+			stepY = 1
+		}
+
+		// 以锚点处的水印为基准向四周铺满
+		x0, y0 := opt.Anchor.offset(width, height, markWidth, markHeight)
+		x0, y0 = x0+opt.OffsetX, y0+opt.OffsetY
+		rowStart := -int(math.Ceil(float64(y0)/float64(stepY))) - 1
+		colStart := -int(math.Ceil(float64(x0)/float64(stepX))) - 1
+		for row := rowStart; y0+row*stepY < height; row++ {
+			shift := 0
+			if opt.Tile == TileDiagonal && row%2 != 0 {
+				shift = stepX / 2
+			}
+			for col := colStart; x0+col*stepX+shift < width; col++ {
+				blendMatrix(imgMatrix, markMatrix, x0+col*stepX+shift, y0+row*stepY, BlendNormal, opt.Opacity)
+			}
+		}
+	}
+
+	return &ImgLoader{
+		filename: il.GetFileName(),
+		format: il.GetFormat(),
+		matrix: imgMatrix,
+		img: il.GetImg(),
+	}
+}
+
+// 给 dir 目录下除水印自身外的所有 jpg、png 图片加水印，以 "watermark-文件名.png" 保存到 outDir，返回处理的图片数。
+// 无法解码的图片被跳过，按文件名记录在 failed 中；保存失败时中止并返回错误
+func (ip *ImgProcessor)WatermarkDir(dir, outDir string, mark *ImgLoader, opt WatermarkOptions) (count int, failed map[string]error, err error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, file := range files {
+		ext := strings.ToLower(filepath.Ext(file.Name()))
+		if file.IsDir() || (ext != ".jpg" && ext != ".jpeg" && ext != ".png") || strings.Split(file.Name(), ".")[0] == mark.GetFileName() {
+			continue
+		}
+
+		il, err := NewImgLoader(path.Join(dir, file.Name()))
+		if err != nil {
+			if failed == nil {
+				failed = make(map[string]error)
+			}
+			failed[file.Name()] = err
+			continue
+		}
+		result := ip.Watermark(&il, mark, opt)
+		err = SaveAsPng(path.Join(outDir, "watermark-"+result.GetFileName()+".png"), result.GetMatrix())
+		if err != nil {
+			return count, failed, err
+		}
+		count++
+	}
+
+	return count, failed, nil
+}
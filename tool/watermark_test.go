@@ -0,0 +1,43 @@
+package tool
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWatermarkDirSkipsUndecodable(t *testing.T) {
+	dir, outDir := tempDir(t), tempDir(t)
+	defer os.RemoveAll(dir)
+	defer os.RemoveAll(outDir)
+
+	writeFixture(t, filepath.Join(dir, "a.png"), 1)
+	writeFixture(t, filepath.Join(dir, "c.png"), 2)
+	writeFixture(t, filepath.Join(dir, "mark.png"), 3)
+	// 排在 a 与 c 之间，旧实现会在这里中止
+	if err := ioutil.WriteFile(filepath.Join(dir, "b.jpg"), []byte("not an image"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	mark, err := NewImgLoader(filepath.Join(dir, "mark.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, failed, err := new(ImgProcessor).WatermarkDir(dir, outDir, &mark, DefaultWatermarkOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want 2", count)
+	}
+	if len(failed) != 1 || failed["b.jpg"] == nil {
+		t.Errorf("failed = %v, want only b.jpg", failed)
+	}
+
+	for _, name := range []string{"watermark-a.png", "watermark-c.png"} {
+		if _, err = os.Stat(filepath.Join(outDir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package tool
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// 白平衡校正，增益都在线性光空间中作用于 R、G、B
+
+// 灰度世界：假设整幅图的平均颜色为灰色，以 G 通道均值为基准
+func (ip *ImgProcessor)GrayWorldWB(il *ImgLoader) *ImgLoader {
+	mean := linearMeans(il.GetMatrix(), 0, 0, il.GetMX(), il.GetMY())
+
+	return applyGains(il, [3]float64{mean[1] / mean[0], 1, mean[1] / mean[2]})
+}
+
+// 白点（max-RGB）：假设各通道的最大值对应白色
+func (ip *ImgProcessor)WhitePatchWB(il *ImgLoader) *ImgLoader {
+	return ip.PercentileWB(il, 100)
+}
+
+// 百分位白点：以各通道第 percentile 百分位的值作为白色，比 max-RGB 更不易受高光噪点影响。
+// percentile 范围 [50, 100]，常用 97 ~ 99
+func (ip *ImgProcessor)PercentileWB(il *ImgLoader, percentile float64) *ImgLoader {
+	percentile = math.Max(50, math.Min(100, percentile)) / 100
+	hist := ip.GetHistogram(il)
+
+	var gains [3]float64
+	for c, bins := range []*[256]int{&hist.R, &hist.G, &hist.B} {
+		cum := cdf(*bins)
+		white := sort.Search(256, func(i int) bool { return cum[i] >= percentile })
+		if white > 255 {
+			white = 255
+		}
+		gains[c] = 1 / math.Max(SRGBToLinear(float64(white)/255), 1e-3)
+	}
+
+	return applyGains(il, gains)
+}
+
+// 按色温与色调手动校正。kelvin 为拍摄时光源的色温，范围 [1000, 40000]，
+// 校正后该光源被映射为 6500K 的白色；tint 范围 [-1, 1]，正值偏品红，负值偏绿
+func (ip *ImgProcessor)TemperatureWB(il *ImgLoader, kelvin, tint float64) *ImgLoader {
+	kelvin = math.Max(1000, math.Min(40000, kelvin))
+	tint = math.Max(-1, math.Min(1, tint))
+
+	src, dst := KelvinToRGB(kelvin), KelvinToRGB(6500)
+	var gains [3]float64
+	for c := range gains {
+		gains[c] = SRGBToLinear(dst[c]) / math.Max(SRGBToLinear(src[c]), 1e-3)
+	}
+	// 以 G 为基准归一化，保持整体亮度
+	gains[0], gains[2] = gains[0]/gains[1], gains[2]/gains[1]
+	gains[1] = 1 - 0.3*tint
+
+	return applyGains(il, gains)
+}
+
+// 指定中性色：以 (x, y) 为中心、边长 2*radius+1 的区域平均颜色应为灰色
+func (ip *ImgProcessor)NeutralPickWB(il *ImgLoader, x, y, radius int) (*ImgLoader, error) {
+	if x < 0 || y < 0 || x >= il.GetMX() || y >= il.GetMY() {
+		return nil, errors.New("neutral point out of range")
+	}
+	if radius < 0 {
+		radius = 0
+	}
+
+	x0, y0 := clampInt(x-radius, 0, il.GetMX()), clampInt(y-radius, 0, il.GetMY())
+	x1, y1 := clampInt(x+radius+1, 0, il.GetMX()), clampInt(y+radius+1, 0, il.GetMY())
+	mean := linearMeans(il.GetMatrix(), x0, y0, x1, y1)
+
+	gray := (mean[0] + mean[1] + mean[2]) / 3
+	var gains [3]float64
+	for c := range gains {
+		gains[c] = gray / math.Max(mean[c], 1e-3)
+	}
+
+	return applyGains(il, gains), nil
+}
+
+// 黑体在给定色温下的近似颜色（sRGB，[0, 1]），参考 Tanner Helland 的拟合公式
+func KelvinToRGB(kelvin float64) (rgb [3]float64) {
+	t := kelvin / 100
+
+	if t <= 66 {
+		rgb[0] = 255
+		rgb[1] = 99.4708025861*math.Log(t) - 161.1195681661
+	} else {
+		rgb[0] = 329.698727446 * math.Pow(t-60, -0.1332047592)
+		rgb[1] = 288.1221695283 * math.Pow(t-60, -0.0755148492)
+	}
+
+	switch {
+	case t >= 66:
+		rgb[2] = 255
+	case t <= 19:
+		rgb[2] = 0
+	default:
+		rgb[2] = 138.5177312231*math.Log(t-10) - 305.0447927307
+	}
+
+	for c := range rgb {
+		rgb[c] = math.Max(0, math.Min(255, rgb[c])) / 255
+	}
+
+	return
+}
+
+// 矩形区域 [x0, x1) x [y0, y1) 内各通道在线性光空间的均值
+func linearMeans(matrix [][][]uint8, x0, y0, x1, y1 int) (mean [3]float64) {
+	var toLinear [256]float64
+	for i := range toLinear {
+		toLinear[i] = SRGBToLinear(float64(i) / 255)
+	}
+
+	for hi := y0; hi < y1; hi++ {
+		for wi := x0; wi < x1; wi++ {
+			for c := range mean {
+				mean[c] += toLinear[matrix[hi][wi][c]]
+			}
+		}
+	}
+
+	n := float64((x1 - x0) * (y1 - y0))
+	for c := range mean {
+		mean[c] = math.Max(mean[c]/n, 1e-6)
+	}
+
+	return
+}
+
+// 在线性光空间中为各通道乘以增益
+func applyGains(il *ImgLoader, gains [3]float64) *ImgLoader {
+	var luts [3][256]uint8
+	for c := range luts {
+		for i := range luts[c] {
+			linear := math.Min(1, SRGBToLinear(float64(i)/255)*gains[c])
+			luts[c][i] = clampUint8(LinearToSRGB(linear) * 255)
+		}
+	}
+
+	return applyLUT(il, luts)
+}